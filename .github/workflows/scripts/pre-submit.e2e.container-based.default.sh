@@ -29,7 +29,7 @@ WORKFLOW_PATH=$(echo "${GITHUB_WORKFLOW_REF%@*}" | cut -d'/' -f3-)
 PREDICATE_CONTENT=$(echo "$ATTESTATION" | jq -r '.predicate')
 e2e_verify_common_all_v1 "$PREDICATE_CONTENT"
 e2e_verify_predicate_v1_runDetails_builder_id "$PREDICATE_CONTENT" "https://github.com/$GITHUB_HEAD_REPOSITORY/$WORKFLOW_PATH@$HEAD_SHA"
-e2e_verify_predicate_v1_buildDefinition_buildType "$PREDICATE_CONTENT" "https://slsa.dev/container-based-build/v0.1?draft"
+e2e_verify_predicate_v1_buildDefinition_buildType "$PREDICATE_CONTENT" "https://github.com/slsa-framework/slsa-github-generator/container-based@v1"
 
 # Verify source: note that the source ref in the uri for pull request events is omitted,
 # the source digest is present. Checking out at a pull request merge reference is not
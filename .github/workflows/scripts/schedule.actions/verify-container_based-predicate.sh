@@ -25,7 +25,7 @@ echo "PREDICATE_CONTENT: $PREDICATE_CONTENT"
 
 # Verify common predicate fields.
 e2e_verify_common_all_v1 "$PREDICATE_CONTENT"
-e2e_verify_predicate_v1_buildDefinition_buildType "$PREDICATE_CONTENT" "https://slsa.dev/container-based-build/v0.1?draft"
+e2e_verify_predicate_v1_buildDefinition_buildType "$PREDICATE_CONTENT" "https://github.com/slsa-framework/slsa-github-generator/container-based@v1"
 e2e_verify_predicate_v1_runDetails_builder_id "$PREDICATE_CONTENT" "https://github.com/$GITHUB_REPOSITORY/.github/workflows/e2e.create-container_based-predicate.schedule.yml@$GITHUB_REF"
 
 # Verify source
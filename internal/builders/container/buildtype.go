@@ -0,0 +1,67 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/builders/common"
+	"github.com/slsa-framework/slsa-github-generator/internal/builders/container/pkg"
+)
+
+// ContainerBuild is a container build type that records the base images,
+// build arguments and Dockerfile of a BuildKit build.
+type ContainerBuild struct {
+	*common.GenericBuild
+
+	// Info is the build information extracted from BuildKit metadata. It may
+	// be nil if no metadata was provided.
+	Info *pkg.BuildInfo
+}
+
+// ContainerBuildConfig is the buildConfig of a ContainerBuild.
+type ContainerBuildConfig struct {
+	// Dockerfile is the path of the Dockerfile used for the build.
+	Dockerfile string `json:"dockerfile,omitempty"`
+
+	// BuildArgs are the build arguments passed to the build.
+	BuildArgs map[string]string `json:"buildArgs,omitempty"`
+}
+
+// BuildConfig implements BuildType.BuildConfig.
+func (b *ContainerBuild) BuildConfig(ctx context.Context) (any, error) {
+	if b.Info == nil {
+		return b.GenericBuild.BuildConfig(ctx)
+	}
+	return ContainerBuildConfig{
+		Dockerfile: b.Info.Dockerfile,
+		BuildArgs:  b.Info.BuildArgs,
+	}, nil
+}
+
+// Materials implements BuildType.Materials. The base images are added after
+// the source repository.
+func (b *ContainerBuild) Materials(ctx context.Context) ([]slsacommon.ProvenanceMaterial, error) {
+	materials, err := b.GenericBuild.Materials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if b.Info != nil {
+		materials = append(materials, b.Info.BaseImages...)
+	}
+	return materials, nil
+}
@@ -17,25 +17,43 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	"github.com/spf13/cobra"
 
 	"github.com/slsa-framework/slsa-github-generator/github"
 	"github.com/slsa-framework/slsa-github-generator/internal/builders/common"
+	"github.com/slsa-framework/slsa-github-generator/internal/builders/container/pkg"
 	"github.com/slsa-framework/slsa-github-generator/internal/utils"
 	"github.com/slsa-framework/slsa-github-generator/slsa"
 )
 
 // generateCmd returns the 'generate' command.
-func generateCmd(provider slsa.ClientProvider, check func(error)) *cobra.Command {
+func generateCmd(provider slsa.ClientProvider, check func(error), opts ...remote.Option) *cobra.Command {
 	var predicatePath string
+	var statementPath string
+	var image string
+	var expandIndex bool
+	var buildxMetadataPath string
+	var buildKitProvenancePath string
 
 	c := &cobra.Command{
 		Use:   "generate",
 		Short: "Create a SLSA provenance predicate from a GitHub Action",
 		Long: `Generate SLSA provenance predicate from a GitHub Action. This command assumes
-that it is being run in the context of a Github Actions workflow.`,
+that it is being run in the context of a Github Actions workflow.
+
+If --image is given, the image reference is resolved to its manifest or index
+digest and the complete unsigned in-toto statement is also written. With
+--expand-index, the images of each platform of a multi-platform index are
+added as subjects.
+
+The base images, build arguments and Dockerfile of the build are recorded if
+the file written by 'docker buildx build --metadata-file' or the provenance
+generated by BuildKit is given.`,
 
 		Run: func(_ *cobra.Command, _ []string) {
 			ghContext, err := github.GetWorkflowContext()
@@ -44,12 +62,28 @@ that it is being run in the context of a Github Actions workflow.`,
 			varsContext, err := github.GetVarsContext()
 			check(err)
 
+			if image == "" && expandIndex {
+				check(errors.New("--expand-index requires --image"))
+			}
+
 			ctx := context.Background()
 
-			b := common.GenericBuild{
-				// NOTE: Subjects are nil because we are only writing the predicate.
-				GithubActionsBuild: slsa.NewGithubActionsBuild(nil, &ghContext, varsContext),
-				BuildTypeURI:       containerBuildType,
+			// NOTE: Subjects are nil if we are only writing the predicate.
+			var subjects []intoto.Subject
+			if image != "" {
+				subjects, err = pkg.ImageSubjects(image, expandIndex, append([]remote.Option{remote.WithContext(ctx)}, opts...)...)
+				check(err)
+			}
+
+			info, err := readBuildInfo(buildxMetadataPath, buildKitProvenancePath)
+			check(err)
+
+			b := ContainerBuild{
+				GenericBuild: &common.GenericBuild{
+					GithubActionsBuild: slsa.NewGithubActionsBuild(subjects, &ghContext, varsContext),
+					BuildTypeURI:       containerBuildType,
+				},
+				Info: info,
 			}
 
 			if provider != nil {
@@ -78,6 +112,19 @@ that it is being run in the context of a Github Actions workflow.`,
 
 			_, err = pf.Write(pb)
 			check(err)
+
+			if image == "" {
+				return
+			}
+
+			sb, err := json.Marshal(p)
+			check(err)
+
+			sf, err := utils.CreateNewFileUnderCurrentDirectory(statementPath, os.O_WRONLY)
+			check(err)
+
+			_, err = sf.Write(sb)
+			check(err)
 		},
 	}
 
@@ -86,6 +133,62 @@ that it is being run in the context of a Github Actions workflow.`,
 		"predicate", "p", "predicate.json",
 		"Path to write the unsigned provenance predicate.",
 	)
+	c.Flags().StringVarP(
+		&image, "image", "i", "",
+		"Image reference to resolve to the statement subjects, e.g. ghcr.io/owner/repo:tag.",
+	)
+	c.Flags().BoolVar(
+		&expandIndex, "expand-index", false,
+		"Add the image of each platform of a multi-platform index as a subject.",
+	)
+	c.Flags().StringVarP(
+		&statementPath, "statement", "s", "statement.json",
+		"Path to write the unsigned in-toto statement when --image is set.",
+	)
+	c.Flags().StringVar(
+		&buildxMetadataPath, "buildx-metadata", "",
+		"Path to the file written by 'docker buildx build --metadata-file'.",
+	)
+	c.Flags().StringVar(
+		&buildKitProvenancePath, "buildkit-provenance", "",
+		"Path to the SLSA v0.2 provenance generated by BuildKit.",
+	)
 
 	return c
 }
+
+// readBuildInfo reads the build information from the buildx metadata file
+// and the BuildKit provenance. It returns nil if neither is given.
+func readBuildInfo(buildxMetadataPath, buildKitProvenancePath string) (*pkg.BuildInfo, error) {
+	var info *pkg.BuildInfo
+	for _, f := range []struct {
+		path  string
+		parse func([]byte) (*pkg.BuildInfo, error)
+	}{
+		{buildxMetadataPath, pkg.ParseBuildxMetadata},
+		{buildKitProvenancePath, pkg.ParseBuildKitProvenance},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if err := utils.PathIsUnderCurrentDirectory(f.path); err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+		i, err := f.parse(b)
+		if err != nil {
+			return nil, err
+		}
+		if info == nil {
+			info = i
+			continue
+		}
+		if err := info.Merge(i); err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
@@ -16,11 +16,23 @@ package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
+	"log"
+	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+
 	"github.com/slsa-framework/slsa-github-generator/internal/utils"
 	"github.com/slsa-framework/slsa-github-generator/slsa"
 )
@@ -154,3 +166,145 @@ func Test_generateCmd_invalid_path(t *testing.T) {
 	// If no error occurs we catch it here. SkipNow will exit the test process so this code should be unreachable.
 	t.Errorf("expected an error to occur.")
 }
+
+func Test_generateCmd_image(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	s := httptest.NewServer(registry.New(registry.Logger(log.New(io.Discard, "", 0))))
+	defer s.Close()
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	ref, err := name.ParseReference(u.Host + "/test/image:latest")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	digest, err := img.Digest()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	c := generateCmd(&slsa.NilClientProvider{}, checkTest(t))
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--image", ref.String(), "--expand-index"})
+	if err := c.Execute(); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+
+	// check that the expected files exist.
+	if _, err := os.Stat(filepath.Join(dir, "predicate.json")); err != nil {
+		t.Errorf("error checking file: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "statement.json"))
+	if err != nil {
+		t.Fatalf("error reading file: %v", err)
+	}
+	var statement intoto.Statement
+	if err := json.Unmarshal(b, &statement); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	want := []intoto.Subject{
+		{Name: ref.Context().Name(), Digest: map[string]string{"sha256": digest.Hex}},
+	}
+	if diff := cmp.Diff(want, statement.Subject); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+}
+
+func Test_generateCmd_buildx_metadata(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	metadata := `{"buildx.build.provenance": {
+		"materials": [{"uri": "pkg:docker/alpine@3.18", "digest": {"sha256": "bbbb"}}],
+		"invocation": {
+			"configSource": {"entryPoint": "Dockerfile"},
+			"parameters": {"args": {"build-arg:VERSION": "1.0"}}
+		}
+	}}`
+	if err := os.WriteFile("metadata.json", []byte(metadata), 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	c := generateCmd(&slsa.NilClientProvider{}, checkTest(t))
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{"--buildx-metadata", "metadata.json"})
+	if err := c.Execute(); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "predicate.json"))
+	if err != nil {
+		t.Fatalf("error reading file: %v", err)
+	}
+	var predicate struct {
+		BuildConfig ContainerBuildConfig `json:"buildConfig"`
+		Materials   []struct {
+			URI string `json:"uri"`
+		} `json:"materials"`
+	}
+	if err := json.Unmarshal(b, &predicate); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	wantConfig := ContainerBuildConfig{
+		Dockerfile: "Dockerfile",
+		BuildArgs:  map[string]string{"VERSION": "1.0"},
+	}
+	if diff := cmp.Diff(wantConfig, predicate.BuildConfig); diff != "" {
+		t.Errorf("unexpected build config (-want +got):\n%s", diff)
+	}
+	var uris []string
+	for _, m := range predicate.Materials {
+		uris = append(uris, m.URI)
+	}
+	if diff := cmp.Diff([]string{"pkg:docker/alpine@3.18"}, uris); diff != "" {
+		t.Errorf("unexpected materials (-want +got):\n%s", diff)
+	}
+}
@@ -23,6 +23,8 @@ import (
 	_ "github.com/sigstore/cosign/v2/pkg/providers/github"
 
 	"github.com/spf13/cobra"
+
+	"github.com/slsa-framework/slsa-github-generator/signing/sigstore"
 )
 
 // containerBuildType is the URI for generic container SLSA generation.
@@ -46,7 +48,8 @@ For more information on SLSA, visit https://slsa.dev`,
 		},
 	}
 	c.AddCommand(versionCmd())
-	c.AddCommand(generateCmd(nil, checkExit))
+	c.AddCommand(generateCmd(nil, checkExit, defaultRemoteOptions()...))
+	c.AddCommand(publishCmd(checkExit, sigstore.NewDefaultBundleSigner(), defaultRemoteOptions()...))
 	return c
 }
 
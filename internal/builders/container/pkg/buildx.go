@@ -0,0 +1,244 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the functionality for extracting information about a
+// container image build from the outputs of BuildKit: the file written by
+// `docker buildx build --metadata-file` and the SLSA v0.2 provenance
+// generated by BuildKit.
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+)
+
+const (
+	// buildxProvenanceKey is the key of the provenance in the buildx metadata
+	// file. Multi-platform builds use one key per platform, with the platform
+	// appended after a slash.
+	buildxProvenanceKey = "buildx.build.provenance"
+
+	// buildArgPrefix is the prefix of build arguments in the BuildKit
+	// frontend parameters.
+	buildArgPrefix = "build-arg:"
+
+	// dockerPURLPrefix is the prefix of materials that are container images.
+	dockerPURLPrefix = "pkg:docker/"
+)
+
+var (
+	// errBuildKitMetadata indicates invalid BuildKit metadata.
+	errBuildKitMetadata = errors.New("buildkit metadata")
+
+	// errBuildKitConflict indicates that the provenance of several platforms
+	// disagree about the build parameters.
+	errBuildKitConflict = errors.New("conflicting buildkit metadata")
+)
+
+// BuildInfo holds information about a container image build.
+type BuildInfo struct {
+	// Dockerfile is the path of the Dockerfile used for the build.
+	Dockerfile string
+
+	// BuildArgs are the build arguments passed to the build.
+	BuildArgs map[string]string
+
+	// BaseImages are the container images used by the build, as purls with
+	// their digests.
+	BaseImages []slsacommon.ProvenanceMaterial
+}
+
+// buildKitProvenance holds the fields used from the SLSA v0.2 provenance
+// generated by BuildKit.
+type buildKitProvenance struct {
+	Materials  []slsacommon.ProvenanceMaterial `json:"materials"`
+	Invocation struct {
+		ConfigSource struct {
+			EntryPoint string `json:"entryPoint"`
+		} `json:"configSource"`
+		Parameters struct {
+			Args map[string]string `json:"args"`
+		} `json:"parameters"`
+	} `json:"invocation"`
+}
+
+// ParseBuildxMetadata parses the file written by
+// `docker buildx build --metadata-file`. The build must have been run with
+// provenance enabled.
+func ParseBuildxMetadata(b []byte) (*BuildInfo, error) {
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal(b, &metadata); err != nil {
+		return nil, fmt.Errorf("%w: %w", errBuildKitMetadata, err)
+	}
+
+	// Sort the keys so that errors are deterministic.
+	var keys []string
+	for k := range metadata {
+		if k == buildxProvenanceKey || strings.HasPrefix(k, buildxProvenanceKey+"/") {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no %q found, was the image built with provenance enabled?",
+			errBuildKitMetadata, buildxProvenanceKey)
+	}
+	sort.Strings(keys)
+
+	info := &BuildInfo{}
+	for _, k := range keys {
+		p, err := parseBuildKitPredicate(metadata[k])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		if err := info.merge(p); err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+	}
+	return info, nil
+}
+
+// ParseBuildKitProvenance parses SLSA v0.2 provenance generated by BuildKit.
+// Both the predicate and the in-toto statement are accepted.
+func ParseBuildKitProvenance(b []byte) (*BuildInfo, error) {
+	var statement struct {
+		Predicate json.RawMessage `json:"predicate"`
+	}
+	if err := json.Unmarshal(b, &statement); err != nil {
+		return nil, fmt.Errorf("%w: %w", errBuildKitMetadata, err)
+	}
+	if statement.Predicate != nil {
+		b = statement.Predicate
+	}
+
+	p, err := parseBuildKitPredicate(b)
+	if err != nil {
+		return nil, err
+	}
+	info := &BuildInfo{}
+	if err := info.merge(p); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Merge adds the information of other to the BuildInfo. Both must agree on
+// the Dockerfile and the build arguments.
+func (i *BuildInfo) Merge(other *BuildInfo) error {
+	if other == nil {
+		return nil
+	}
+	if err := mergeDockerfile(i, other.Dockerfile); err != nil {
+		return err
+	}
+	if err := mergeBuildArgs(i, other.BuildArgs); err != nil {
+		return err
+	}
+	i.BaseImages = mergeMaterials(i.BaseImages, other.BaseImages)
+	return nil
+}
+
+// merge adds the information of a BuildKit provenance predicate.
+func (i *BuildInfo) merge(p *buildKitProvenance) error {
+	args := map[string]string{}
+	for k, v := range p.Invocation.Parameters.Args {
+		if name, ok := strings.CutPrefix(k, buildArgPrefix); ok {
+			args[name] = v
+		}
+	}
+
+	var images []slsacommon.ProvenanceMaterial
+	for _, m := range p.Materials {
+		if strings.HasPrefix(m.URI, dockerPURLPrefix) {
+			images = append(images, m)
+		}
+	}
+
+	return i.Merge(&BuildInfo{
+		Dockerfile: p.Invocation.ConfigSource.EntryPoint,
+		BuildArgs:  args,
+		BaseImages: images,
+	})
+}
+
+// parseBuildKitPredicate parses a SLSA v0.2 predicate generated by BuildKit.
+func parseBuildKitPredicate(b []byte) (*buildKitProvenance, error) {
+	var p buildKitProvenance
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, fmt.Errorf("%w: %w", errBuildKitMetadata, err)
+	}
+	for _, m := range p.Materials {
+		if strings.HasPrefix(m.URI, dockerPURLPrefix) && len(m.Digest) == 0 {
+			return nil, fmt.Errorf("%w: base image %q has no digest", errBuildKitMetadata, m.URI)
+		}
+	}
+	return &p, nil
+}
+
+func mergeDockerfile(i *BuildInfo, dockerfile string) error {
+	switch {
+	case dockerfile == "" || dockerfile == i.Dockerfile:
+	case i.Dockerfile == "":
+		i.Dockerfile = dockerfile
+	default:
+		return fmt.Errorf("%w: Dockerfile %q and %q", errBuildKitConflict, i.Dockerfile, dockerfile)
+	}
+	return nil
+}
+
+func mergeBuildArgs(i *BuildInfo, args map[string]string) error {
+	for k, v := range args {
+		if old, ok := i.BuildArgs[k]; ok && old != v {
+			return fmt.Errorf("%w: build argument %q is %q and %q", errBuildKitConflict, k, old, v)
+		}
+		if i.BuildArgs == nil {
+			i.BuildArgs = map[string]string{}
+		}
+		i.BuildArgs[k] = v
+	}
+	return nil
+}
+
+// mergeMaterials returns the union of the materials, sorted by URI.
+func mergeMaterials(a, b []slsacommon.ProvenanceMaterial) []slsacommon.ProvenanceMaterial {
+	seen := map[string]bool{}
+	var materials []slsacommon.ProvenanceMaterial
+	for _, m := range append(append([]slsacommon.ProvenanceMaterial{}, a...), b...) {
+		key := materialKey(m)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		materials = append(materials, m)
+	}
+	sort.SliceStable(materials, func(i, j int) bool {
+		return materialKey(materials[i]) < materialKey(materials[j])
+	})
+	return materials
+}
+
+// materialKey returns a key identifying the material by URI and digest.
+func materialKey(m slsacommon.ProvenanceMaterial) string {
+	algs := make([]string, 0, len(m.Digest))
+	for alg, v := range m.Digest {
+		algs = append(algs, alg+":"+v)
+	}
+	sort.Strings(algs)
+	return m.URI + "@" + strings.Join(algs, ",")
+}
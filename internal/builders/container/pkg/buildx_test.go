@@ -0,0 +1,176 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+)
+
+const (
+	amd64Provenance = `{
+		"buildType": "https://mobyproject.org/buildkit@v1",
+		"materials": [
+			{"uri": "pkg:docker/golang@1.21?platform=linux%2Famd64", "digest": {"sha256": "aaaa"}},
+			{"uri": "pkg:docker/alpine@3.18?platform=linux%2Famd64", "digest": {"sha256": "bbbb"}},
+			{"uri": "https://github.com/owner/repo.git#main", "digest": {"sha1": "cccc"}}
+		],
+		"invocation": {
+			"configSource": {"entryPoint": "build/Dockerfile"},
+			"parameters": {
+				"frontend": "dockerfile.v0",
+				"args": {"build-arg:VERSION": "1.0", "label:org.example": "x"}
+			}
+		}
+	}`
+
+	arm64Provenance = `{
+		"buildType": "https://mobyproject.org/buildkit@v1",
+		"materials": [
+			{"uri": "pkg:docker/golang@1.21?platform=linux%2Farm64", "digest": {"sha256": "dddd"}}
+		],
+		"invocation": {
+			"configSource": {"entryPoint": "build/Dockerfile"},
+			"parameters": {"args": {"build-arg:VERSION": "1.0"}}
+		}
+	}`
+)
+
+func TestParseBuildxMetadata(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		metadata string
+		want     *BuildInfo
+		expected error
+	}{
+		{
+			name: "single platform",
+			metadata: `{
+				"containerimage.digest": "sha256:1234",
+				"buildx.build.provenance": ` + amd64Provenance + `
+			}`,
+			want: &BuildInfo{
+				Dockerfile: "build/Dockerfile",
+				BuildArgs:  map[string]string{"VERSION": "1.0"},
+				BaseImages: []slsacommon.ProvenanceMaterial{
+					{URI: "pkg:docker/alpine@3.18?platform=linux%2Famd64", Digest: slsacommon.DigestSet{"sha256": "bbbb"}},
+					{URI: "pkg:docker/golang@1.21?platform=linux%2Famd64", Digest: slsacommon.DigestSet{"sha256": "aaaa"}},
+				},
+			},
+		},
+		{
+			name: "multiple platforms",
+			metadata: `{
+				"buildx.build.provenance/linux/amd64": ` + amd64Provenance + `,
+				"buildx.build.provenance/linux/arm64": ` + arm64Provenance + `
+			}`,
+			want: &BuildInfo{
+				Dockerfile: "build/Dockerfile",
+				BuildArgs:  map[string]string{"VERSION": "1.0"},
+				BaseImages: []slsacommon.ProvenanceMaterial{
+					{URI: "pkg:docker/alpine@3.18?platform=linux%2Famd64", Digest: slsacommon.DigestSet{"sha256": "bbbb"}},
+					{URI: "pkg:docker/golang@1.21?platform=linux%2Famd64", Digest: slsacommon.DigestSet{"sha256": "aaaa"}},
+					{URI: "pkg:docker/golang@1.21?platform=linux%2Farm64", Digest: slsacommon.DigestSet{"sha256": "dddd"}},
+				},
+			},
+		},
+		{
+			name: "conflicting build args",
+			metadata: `{
+				"buildx.build.provenance/linux/amd64": ` + amd64Provenance + `,
+				"buildx.build.provenance/linux/arm64": {"invocation": {"parameters": {"args": {"build-arg:VERSION": "2.0"}}}}
+			}`,
+			expected: errBuildKitConflict,
+		},
+		{
+			name:     "no provenance",
+			metadata: `{"containerimage.digest": "sha256:1234"}`,
+			expected: errBuildKitMetadata,
+		},
+		{
+			name:     "base image without digest",
+			metadata: `{"buildx.build.provenance": {"materials": [{"uri": "pkg:docker/alpine@3.18"}]}}`,
+			expected: errBuildKitMetadata,
+		},
+		{
+			name:     "invalid json",
+			metadata: `not json`,
+			expected: errBuildKitMetadata,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseBuildxMetadata([]byte(tt.metadata))
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected build info (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseBuildKitProvenance(t *testing.T) {
+	t.Parallel()
+
+	want := &BuildInfo{
+		Dockerfile: "build/Dockerfile",
+		BuildArgs:  map[string]string{"VERSION": "1.0"},
+		BaseImages: []slsacommon.ProvenanceMaterial{
+			{URI: "pkg:docker/golang@1.21?platform=linux%2Farm64", Digest: slsacommon.DigestSet{"sha256": "dddd"}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		provenance string
+	}{
+		{
+			name:       "predicate",
+			provenance: arm64Provenance,
+		},
+		{
+			name: "statement",
+			provenance: `{
+				"_type": "https://in-toto.io/Statement/v0.1",
+				"predicateType": "https://slsa.dev/provenance/v0.2",
+				"subject": [],
+				"predicate": ` + arm64Provenance + `
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseBuildKitProvenance([]byte(tt.provenance))
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected build info (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the functionality for publishing signed attestations
+// about container images to the registry that hosts the image.
+//
+// Attestations are published as OCI 1.1 referrer artifacts holding a
+// Sigstore bundle. If the registry rejects the referrer manifest, the DSSE
+// envelope contained in the bundle is published using the cosign tag scheme
+// (`sha256-<digest>.att`) instead.
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/static"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	cosignmutate "github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	ociremote "github.com/sigstore/cosign/v2/pkg/oci/remote"
+	cosignstatic "github.com/sigstore/cosign/v2/pkg/oci/static"
+	cosigntypes "github.com/sigstore/cosign/v2/pkg/types"
+
+	"github.com/slsa-framework/slsa-github-generator/signing"
+)
+
+const (
+	// emptyConfigMediaType is the media type of the empty descriptor used as
+	// the config of OCI 1.1 artifacts.
+	emptyConfigMediaType types.MediaType = "application/vnd.oci.empty.v1+json"
+
+	// bundleMediaTypePrefix is the common prefix of Sigstore bundle media types.
+	bundleMediaTypePrefix = "application/vnd.dev.sigstore.bundle"
+
+	// predicateTypeAnnotation records the predicate type on referrer manifests.
+	predicateTypeAnnotation = "dev.sigstore.bundle.predicateType"
+
+	// contentAnnotation records the kind of content held by the bundle.
+	contentAnnotation = "dev.sigstore.bundle.content"
+)
+
+// emptyConfig is the content of the empty descriptor.
+var emptyConfig = []byte("{}")
+
+var (
+	// errSubject indicates that the statement does not refer to the image.
+	errSubject = errors.New("subject")
+
+	// errPublish indicates an error pushing the attestation to the registry.
+	errPublish = errors.New("publish")
+)
+
+// PublishMode selects how attestations are attached to images.
+type PublishMode string
+
+const (
+	// PublishAuto publishes attestations as OCI referrers, and falls back to
+	// the cosign tag scheme if the registry rejects the referrer.
+	PublishAuto PublishMode = "auto"
+
+	// PublishReferrers only publishes attestations as OCI referrers.
+	PublishReferrers PublishMode = "referrers"
+
+	// PublishCosign only publishes attestations using the cosign tag scheme.
+	PublishCosign PublishMode = "cosign"
+)
+
+// ParsePublishMode parses and validates a PublishMode.
+func ParsePublishMode(s string) (PublishMode, error) {
+	switch m := PublishMode(s); m {
+	case PublishAuto, PublishReferrers, PublishCosign:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unsupported publish mode %q, want one of %q, %q, %q",
+			s, PublishAuto, PublishReferrers, PublishCosign)
+	}
+}
+
+// PublishResult describes a published attestation.
+type PublishResult struct {
+	// Mode is the scheme that was used to publish the attestation. It is
+	// either PublishReferrers or PublishCosign.
+	Mode PublishMode
+
+	// Reference is the reference of the published manifest.
+	Reference name.Reference
+}
+
+// Publisher signs statements about container images and pushes the signed
+// attestations to the registry hosting the image.
+type Publisher struct {
+	signer  signing.Signer
+	mode    PublishMode
+	options []remote.Option
+}
+
+// NewPublisher creates a new Publisher that signs statements using the given
+// signer. The remote options are used for all registry operations.
+func NewPublisher(signer signing.Signer, mode PublishMode, opts ...remote.Option) *Publisher {
+	return &Publisher{
+		signer:  signer,
+		mode:    mode,
+		options: opts,
+	}
+}
+
+// Publish signs the statement and attaches the resulting attestation to the
+// image identified by the repository and digest. The statement must have the
+// image digest as one of its subjects.
+func (p *Publisher) Publish(ctx context.Context, image, digest string, statement *intoto.Statement) (*PublishResult, error) {
+	ref, err := name.NewDigest(fmt.Sprintf("%s@%s", image, digest))
+	if err != nil {
+		return nil, fmt.Errorf("parsing image reference: %w", err)
+	}
+	if err := checkSubject(ref, statement); err != nil {
+		return nil, err
+	}
+
+	att, err := p.signer.Sign(ctx, statement)
+	if err != nil {
+		return nil, fmt.Errorf("signing statement: %w", err)
+	}
+
+	opts := append([]remote.Option{remote.WithContext(ctx)}, p.options...)
+
+	if p.mode != PublishCosign {
+		r, err := publishReferrer(ref, att, statement.PredicateType, opts)
+		if err == nil || p.mode == PublishReferrers {
+			return r, err
+		}
+		log.Printf("Publishing the referrer failed, falling back to the cosign tag scheme: %v", err)
+	}
+	return publishCosign(ref, att, statement.PredicateType, opts)
+}
+
+// checkSubject verifies that the image digest is one of the statement subjects.
+func checkSubject(ref name.Digest, statement *intoto.Statement) error {
+	alg, value, ok := strings.Cut(ref.DigestStr(), ":")
+	if !ok {
+		return fmt.Errorf("%w: malformed digest %q", errSubject, ref.DigestStr())
+	}
+	for _, s := range statement.Subject {
+		if s.Digest[alg] == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: statement has no subject with digest %q", errSubject, ref.DigestStr())
+}
+
+// referrerManifest is an OCI 1.1 image manifest. It is defined here because
+// v1.Manifest does not support the artifactType field.
+type referrerManifest struct {
+	SchemaVersion int64             `json:"schemaVersion"`
+	MediaType     types.MediaType   `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType"`
+	Config        v1.Descriptor     `json:"config"`
+	Layers        []v1.Descriptor   `json:"layers"`
+	Subject       *v1.Descriptor    `json:"subject"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// rawManifest implements remote.Taggable for an already serialized manifest.
+type rawManifest struct {
+	raw       []byte
+	mediaType types.MediaType
+}
+
+// RawManifest implements remote.Taggable.RawManifest.
+func (m *rawManifest) RawManifest() ([]byte, error) {
+	return m.raw, nil
+}
+
+// MediaType implements remote.Taggable.MediaType.
+func (m *rawManifest) MediaType() (types.MediaType, error) {
+	return m.mediaType, nil
+}
+
+// publishReferrer pushes the attestation as an OCI 1.1 artifact that refers
+// to the image. Registries that do not support the referrers API are handled
+// using the referrers tag schema.
+func publishReferrer(ref name.Digest, att signing.Attestation, predicateType string, opts []remote.Option) (*PublishResult, error) {
+	subject, err := remote.Head(ref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fetching the image descriptor: %w", errPublish, err)
+	}
+
+	artifactType := attestationMediaType(att.Bytes())
+	config := static.NewLayer(emptyConfig, emptyConfigMediaType)
+	layer := static.NewLayer(att.Bytes(), artifactType)
+	for _, l := range []v1.Layer{config, layer} {
+		if err := remote.WriteLayer(ref.Repository, l, opts...); err != nil {
+			return nil, fmt.Errorf("%w: uploading blob: %w", errPublish, err)
+		}
+	}
+
+	configDesc, err := partialDescriptor(config)
+	if err != nil {
+		return nil, err
+	}
+	layerDesc, err := partialDescriptor(layer)
+	if err != nil {
+		return nil, err
+	}
+
+	m := referrerManifest{
+		SchemaVersion: 2,
+		MediaType:     types.OCIManifestSchema1,
+		ArtifactType:  string(artifactType),
+		Config:        *configDesc,
+		Layers:        []v1.Descriptor{*layerDesc},
+		Subject: &v1.Descriptor{
+			MediaType: subject.MediaType,
+			Digest:    subject.Digest,
+			Size:      subject.Size,
+		},
+		Annotations: map[string]string{
+			predicateTypeAnnotation: predicateType,
+		},
+	}
+	if strings.HasPrefix(string(artifactType), bundleMediaTypePrefix) {
+		m.Annotations[contentAnnotation] = "dsse-envelope"
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling the referrer manifest: %w", err)
+	}
+	h, _, err := v1.SHA256(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("computing the referrer manifest digest: %w", err)
+	}
+
+	target := ref.Context().Digest(h.String())
+	if err := remote.Put(target, &rawManifest{raw: raw, mediaType: types.OCIManifestSchema1}, opts...); err != nil {
+		return nil, fmt.Errorf("%w: pushing the referrer manifest: %w", errPublish, err)
+	}
+	return &PublishResult{Mode: PublishReferrers, Reference: target}, nil
+}
+
+// publishCosign attaches the DSSE envelope of the attestation to the image
+// using the cosign `sha256-<digest>.att` tag scheme.
+func publishCosign(ref name.Digest, att signing.Attestation, predicateType string, opts []remote.Option) (*PublishResult, error) {
+	envelope, err := dsseEnvelope(att.Bytes())
+	if err != nil {
+		return nil, err
+	}
+
+	sigOpts := []cosignstatic.Option{
+		cosignstatic.WithLayerMediaType(cosigntypes.DssePayloadType),
+		cosignstatic.WithAnnotations(map[string]string{
+			"predicateType": predicateType,
+		}),
+	}
+	if cert := att.Cert(); len(cert) > 0 {
+		sigOpts = append(sigOpts, cosignstatic.WithCertChain(certPEM(cert), nil))
+	}
+	sig, err := cosignstatic.NewAttestation(envelope, sigOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating the cosign attestation: %w", err)
+	}
+
+	ociOpts := []ociremote.Option{ociremote.WithRemoteOptions(opts...)}
+	se, err := cosignmutate.AttachAttestationToEntity(ociremote.SignedUnknown(ref, ociOpts...), sig)
+	if err != nil {
+		return nil, fmt.Errorf("attaching the attestation: %w", err)
+	}
+	if err := ociremote.WriteAttestations(ref.Repository, se, ociOpts...); err != nil {
+		return nil, fmt.Errorf("%w: pushing the cosign attestation: %w", errPublish, err)
+	}
+
+	tag, err := ociremote.AttestationTag(ref, ociOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("computing the attestation tag: %w", err)
+	}
+	return &PublishResult{Mode: PublishCosign, Reference: tag}, nil
+}
+
+// bundleEnvelope holds the fields of a Sigstore bundle needed for publishing.
+type bundleEnvelope struct {
+	MediaType    string         `json:"mediaType"`
+	DSSEEnvelope *dsse.Envelope `json:"dsseEnvelope"`
+}
+
+// attestationMediaType returns the media type of a signed attestation, which
+// is either a Sigstore bundle or a plain DSSE envelope.
+func attestationMediaType(b []byte) types.MediaType {
+	var be bundleEnvelope
+	if err := json.Unmarshal(b, &be); err == nil && strings.HasPrefix(be.MediaType, bundleMediaTypePrefix) {
+		return types.MediaType(be.MediaType)
+	}
+	return cosigntypes.DssePayloadType
+}
+
+// dsseEnvelope returns the DSSE envelope of a signed attestation. Sigstore
+// bundles contain the envelope, other attestations are the envelope.
+func dsseEnvelope(b []byte) ([]byte, error) {
+	var be bundleEnvelope
+	if err := json.Unmarshal(b, &be); err != nil {
+		return nil, fmt.Errorf("parsing the attestation: %w", err)
+	}
+	if !strings.HasPrefix(be.MediaType, bundleMediaTypePrefix) {
+		return b, nil
+	}
+	if be.DSSEEnvelope == nil {
+		return nil, errors.New("sigstore bundle does not contain a DSSE envelope")
+	}
+	return json.Marshal(be.DSSEEnvelope)
+}
+
+// certPEM returns the certificate in PEM format. Certificates may be
+// returned by signers either in DER or PEM format.
+func certPEM(cert []byte) []byte {
+	if bytes.HasPrefix(bytes.TrimSpace(cert), []byte("-----BEGIN")) {
+		return cert
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})
+}
+
+// partialDescriptor returns the descriptor of a layer.
+func partialDescriptor(l v1.Layer) (*v1.Descriptor, error) {
+	d, err := l.Digest()
+	if err != nil {
+		return nil, fmt.Errorf("computing the layer digest: %w", err)
+	}
+	sz, err := l.Size()
+	if err != nil {
+		return nil, fmt.Errorf("computing the layer size: %w", err)
+	}
+	mt, err := l.MediaType()
+	if err != nil {
+		return nil, fmt.Errorf("getting the layer media type: %w", err)
+	}
+	return &v1.Descriptor{MediaType: mt, Digest: d, Size: sz}, nil
+}
@@ -0,0 +1,333 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/registry"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/testutil"
+	"github.com/slsa-framework/slsa-github-generator/signing"
+)
+
+const testPredicateType = "https://example.com/test/v1"
+
+// bundleAttestation is a signing.Attestation holding a Sigstore bundle.
+type bundleAttestation struct {
+	bytes []byte
+}
+
+// Cert implements Attestation.Cert.
+func (a *bundleAttestation) Cert() []byte { return nil }
+
+// Bytes implements Attestation.Bytes.
+func (a *bundleAttestation) Bytes() []byte { return a.bytes }
+
+// bundleSigner is a Signer that returns an unsigned Sigstore bundle.
+type bundleSigner struct{}
+
+// Sign implements Signer.Sign.
+func (s *bundleSigner) Sign(_ context.Context, st *intoto.Statement) (signing.Attestation, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(map[string]any{
+		"mediaType": "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"dsseEnvelope": map[string]any{
+			"payload":     base64.StdEncoding.EncodeToString(payload),
+			"payloadType": intoto.PayloadType,
+			"signatures":  []map[string]string{{"sig": "c2ln"}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bundleAttestation{bytes: b}, nil
+}
+
+// noReferrersHandler rejects manifests with a subject, emulating registries
+// without OCI 1.1 support.
+func noReferrersHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && strings.Contains(r.URL.Path, "/manifests/") {
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if bytes.Contains(b, []byte(`"subject"`)) {
+				http.Error(w, "subject not supported", http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(b))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// setupRegistry starts an in-memory registry and returns its host.
+func setupRegistry(t *testing.T, wrap func(http.Handler) http.Handler) string {
+	t.Helper()
+
+	h := registry.New(
+		registry.WithReferrersSupport(true),
+		registry.Logger(log.New(io.Discard, "", 0)),
+	)
+	if wrap != nil {
+		h = wrap(h)
+	}
+	s := httptest.NewServer(h)
+	t.Cleanup(s.Close)
+
+	u, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	return u.Host
+}
+
+// setupImage starts a registry and pushes a random image to it. It returns
+// the image repository and digest.
+func setupImage(t *testing.T, wrap func(http.Handler) http.Handler) (string, string) {
+	t.Helper()
+
+	img, err := random.Image(1024, 1)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	ref, err := name.ParseReference(setupRegistry(t, wrap) + "/test/image:latest")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := remote.Write(ref, img); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	d, err := img.Digest()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	return ref.Context().Name(), d.String()
+}
+
+func testStatement(image, digest string) *intoto.Statement {
+	_, value, _ := strings.Cut(digest, ":")
+	return &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: testPredicateType,
+			Subject: []intoto.Subject{
+				{Name: image, Digest: map[string]string{"sha256": value}},
+			},
+		},
+		Predicate: map[string]string{"foo": "bar"},
+	}
+}
+
+func TestParsePublishMode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		mode    string
+		want    PublishMode
+		wantErr bool
+	}{
+		{name: "auto", mode: "auto", want: PublishAuto},
+		{name: "referrers", mode: "referrers", want: PublishReferrers},
+		{name: "cosign", mode: "cosign", want: PublishCosign},
+		{name: "unknown", mode: "tags", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParsePublishMode(tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected mode, got: %q, want: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		signer   signing.Signer
+		mode     PublishMode
+		wrap     func(http.Handler) http.Handler
+		wantMode PublishMode
+		expected error
+	}{
+		{
+			name:     "referrer bundle",
+			signer:   &bundleSigner{},
+			mode:     PublishAuto,
+			wantMode: PublishReferrers,
+		},
+		{
+			name:     "referrer dsse envelope",
+			signer:   &testutil.TestSigner{Att: testutil.TestAttestation{BytesVal: []byte(`{"payloadType":"x"}`)}},
+			mode:     PublishReferrers,
+			wantMode: PublishReferrers,
+		},
+		{
+			name:     "fallback to cosign",
+			signer:   &bundleSigner{},
+			mode:     PublishAuto,
+			wrap:     noReferrersHandler,
+			wantMode: PublishCosign,
+		},
+		{
+			name:     "referrers only",
+			signer:   &bundleSigner{},
+			mode:     PublishReferrers,
+			wrap:     noReferrersHandler,
+			expected: errPublish,
+		},
+		{
+			name:     "cosign",
+			signer:   &bundleSigner{},
+			mode:     PublishCosign,
+			wantMode: PublishCosign,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			image, digest := setupImage(t, tt.wrap)
+			p := NewPublisher(tt.signer, tt.mode)
+			r, err := p.Publish(context.Background(), image, digest, testStatement(image, digest))
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if err != nil {
+				return
+			}
+			if r.Mode != tt.wantMode {
+				t.Errorf("unexpected mode, got: %q, want: %q", r.Mode, tt.wantMode)
+			}
+
+			ref, err := name.NewDigest(image + "@" + digest)
+			if err != nil {
+				t.Fatalf("unexpected failure: %v", err)
+			}
+			switch r.Mode {
+			case PublishReferrers:
+				idx, err := remote.Referrers(ref)
+				if err != nil {
+					t.Fatalf("unexpected failure: %v", err)
+				}
+				m, err := idx.IndexManifest()
+				if err != nil {
+					t.Fatalf("unexpected failure: %v", err)
+				}
+				if got, want := len(m.Manifests), 1; got != want {
+					t.Fatalf("unexpected number of referrers, got: %d, want: %d", got, want)
+				}
+				if got, want := m.Manifests[0].Digest.String(), r.Reference.Identifier(); got != want {
+					t.Errorf("unexpected referrer, got: %q, want: %q", got, want)
+				}
+				desc, err := remote.Get(r.Reference)
+				if err != nil {
+					t.Fatalf("unexpected failure: %v", err)
+				}
+				var rm referrerManifest
+				if err := json.Unmarshal(desc.Manifest, &rm); err != nil {
+					t.Fatalf("unexpected failure: %v", err)
+				}
+				if got, want := rm.Annotations[predicateTypeAnnotation], testPredicateType; got != want {
+					t.Errorf("unexpected predicate type, got: %q, want: %q", got, want)
+				}
+			case PublishCosign:
+				want := ref.Context().Tag(strings.Replace(digest, ":", "-", 1) + ".att")
+				if r.Reference.String() != want.String() {
+					t.Errorf("unexpected reference, got: %q, want: %q", r.Reference, want)
+				}
+				if _, err := remote.Head(want); err != nil {
+					t.Errorf("expected the attestation tag to exist: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestPublisher_Publish_subject(t *testing.T) {
+	t.Parallel()
+
+	image, digest := setupImage(t, nil)
+	st := testStatement(image, digest)
+	st.Subject[0].Digest["sha256"] = strings.Repeat("0", 64)
+
+	p := NewPublisher(&bundleSigner{}, PublishAuto)
+	_, err := p.Publish(context.Background(), image, digest, st)
+	if !errors.Is(err, errSubject) {
+		t.Fatalf("unexpected error: %v", cmp.Diff(err, errSubject, cmpopts.EquateErrors()))
+	}
+}
+
+func Test_dsseEnvelope(t *testing.T) {
+	t.Parallel()
+
+	att, err := (&bundleSigner{}).Sign(context.Background(), testStatement("image", "sha256:abc"))
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	b, err := dsseEnvelope(att.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	var env map[string]any
+	if err := json.Unmarshal(b, &env); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if got, want := env["payloadType"], intoto.PayloadType; got != want {
+		t.Errorf("unexpected payload type, got: %v, want: %v", got, want)
+	}
+
+	// Plain DSSE envelopes are returned as is.
+	plain := []byte(`{"payloadType":"x"}`)
+	b, err = dsseEnvelope(plain)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if diff := cmp.Diff(plain, b); diff != "" {
+		t.Errorf("unexpected envelope (-want +got):\n%s", diff)
+	}
+}
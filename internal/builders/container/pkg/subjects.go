@@ -0,0 +1,81 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+// referenceTypeAnnotation is set by BuildKit on index entries that hold
+// attestations rather than platform images.
+const referenceTypeAnnotation = "vnd.docker.reference.type"
+
+// errImage indicates an image reference that cannot be resolved.
+var errImage = errors.New("image")
+
+// ImageSubjects resolves the image reference to the digest of its manifest or
+// index and returns it as an in-toto subject. If expandIndex is true and the
+// reference points to a multi-platform index, a subject is also returned for
+// the image of each platform. All subjects are named after the repository.
+//
+// If the reference includes a digest, the registry content is verified to
+// match it.
+func ImageSubjects(image string, expandIndex bool, opts ...remote.Option) ([]intoto.Subject, error) {
+	ref, err := name.ParseReference(image)
+	if err != nil {
+		return nil, fmt.Errorf("%w: parsing reference %q: %w", errImage, image, err)
+	}
+
+	desc, err := remote.Get(ref, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: fetching %q: %w", errImage, image, err)
+	}
+
+	repo := ref.Context().Name()
+	subjects := []intoto.Subject{toIntotoSubject(repo, desc.Digest)}
+	if !expandIndex || !desc.MediaType.IsIndex() {
+		return subjects, nil
+	}
+
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading index %q: %w", errImage, image, err)
+	}
+	m, err := idx.IndexManifest()
+	if err != nil {
+		return nil, fmt.Errorf("%w: reading index %q: %w", errImage, image, err)
+	}
+	for _, d := range m.Manifests {
+		if !d.MediaType.IsImage() || d.Annotations[referenceTypeAnnotation] != "" {
+			continue
+		}
+		subjects = append(subjects, toIntotoSubject(repo, d.Digest))
+	}
+	return subjects, nil
+}
+
+// toIntotoSubject converts an image digest to an in-toto subject.
+func toIntotoSubject(name string, h v1.Hash) intoto.Subject {
+	return intoto.Subject{
+		Name:   name,
+		Digest: map[string]string{h.Algorithm: h.Hex},
+	}
+}
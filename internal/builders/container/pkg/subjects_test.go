@@ -0,0 +1,151 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/random"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/types"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+// setupIndex pushes a multi-platform index with an additional attestation
+// manifest. It returns the index reference and the expected subjects.
+func setupIndex(t *testing.T) (name.Reference, []intoto.Subject) {
+	t.Helper()
+
+	ref, err := name.ParseReference(setupRegistry(t, nil) + "/test/index:v1")
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	repo := ref.Context().Name()
+
+	var adds []mutate.IndexAddendum
+	var platforms []intoto.Subject
+	for _, arch := range []string{"amd64", "arm64"} {
+		img, err := random.Image(512, 1)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		d, err := img.Digest()
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		adds = append(adds, mutate.IndexAddendum{
+			Add: img,
+			Descriptor: v1.Descriptor{
+				Platform: &v1.Platform{OS: "linux", Architecture: arch},
+			},
+		})
+		platforms = append(platforms, toIntotoSubject(repo, d))
+	}
+
+	att, err := random.Image(128, 1)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	adds = append(adds, mutate.IndexAddendum{
+		Add: att,
+		Descriptor: v1.Descriptor{
+			Platform:    &v1.Platform{OS: "unknown", Architecture: "unknown"},
+			Annotations: map[string]string{referenceTypeAnnotation: "attestation-manifest"},
+		},
+	})
+
+	idx := mutate.IndexMediaType(mutate.AppendManifests(empty.Index, adds...), types.OCIImageIndex)
+	if err := remote.WriteIndex(ref, idx); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	d, err := idx.Digest()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	return ref, append([]intoto.Subject{toIntotoSubject(repo, d)}, platforms...)
+}
+
+func TestImageSubjects(t *testing.T) {
+	t.Parallel()
+
+	t.Run("image", func(t *testing.T) {
+		t.Parallel()
+
+		image, digest := setupImage(t, nil)
+		subjects, err := ImageSubjects(image+":latest", true)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		want := []intoto.Subject{
+			{Name: image, Digest: map[string]string{"sha256": strings.TrimPrefix(digest, "sha256:")}},
+		}
+		if diff := cmp.Diff(want, subjects); diff != "" {
+			t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("index", func(t *testing.T) {
+		t.Parallel()
+
+		ref, want := setupIndex(t)
+		subjects, err := ImageSubjects(ref.String(), false)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		if diff := cmp.Diff(want[:1], subjects); diff != "" {
+			t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("expand index", func(t *testing.T) {
+		t.Parallel()
+
+		ref, want := setupIndex(t)
+		subjects, err := ImageSubjects(ref.String(), true)
+		if err != nil {
+			t.Fatalf("unexpected failure: %v", err)
+		}
+		if diff := cmp.Diff(want, subjects); diff != "" {
+			t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		t.Parallel()
+
+		image, _ := setupImage(t, nil)
+		_, err := ImageSubjects(image+"@sha256:"+strings.Repeat("0", 64), false)
+		if !errors.Is(err, errImage) {
+			t.Fatalf("unexpected error: %v", cmp.Diff(err, errImage, cmpopts.EquateErrors()))
+		}
+	})
+
+	t.Run("invalid reference", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := ImageSubjects("not a reference", false)
+		if !errors.Is(err, errImage) {
+			t.Fatalf("unexpected error: %v", cmp.Diff(err, errImage, cmpopts.EquateErrors()))
+		}
+	})
+}
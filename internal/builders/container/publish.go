@@ -0,0 +1,91 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/spf13/cobra"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/builders/container/pkg"
+	"github.com/slsa-framework/slsa-github-generator/internal/utils"
+	"github.com/slsa-framework/slsa-github-generator/signing"
+)
+
+// publishCmd returns the 'publish' command.
+func publishCmd(check func(error), signer signing.Signer, opts ...remote.Option) *cobra.Command {
+	var image string
+	var digest string
+	var statementPath string
+	var mode string
+
+	c := &cobra.Command{
+		Use:   "publish",
+		Short: "Sign an in-toto statement and attach it to a container image",
+		Long: `Sign an in-toto statement and attach the attestation to a container image.
+The attestation is published as an OCI 1.1 referrer holding a Sigstore bundle.
+If the registry rejects the referrer, the attestation is published using the
+cosign tag scheme instead. The statement must have the image digest as one of
+its subjects.`,
+
+		Run: func(cmd *cobra.Command, _ []string) {
+			if image == "" || digest == "" {
+				check(errors.New("--image and --digest are required"))
+			}
+
+			m, err := pkg.ParsePublishMode(mode)
+			check(err)
+
+			check(utils.PathIsUnderCurrentDirectory(statementPath))
+
+			b, err := os.ReadFile(statementPath)
+			check(err)
+
+			var statement intoto.Statement
+			check(json.Unmarshal(b, &statement))
+
+			p := pkg.NewPublisher(signer, m, opts...)
+			r, err := p.Publish(context.Background(), image, digest, &statement)
+			check(err)
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Published attestation to %s using %s\n", r.Reference, r.Mode)
+		},
+	}
+
+	c.Flags().StringVarP(&image, "image", "i", "", "The image repository, without tag or digest.")
+	c.Flags().StringVarP(&digest, "digest", "d", "", "The image digest, e.g. sha256:<hex>.")
+	c.Flags().StringVarP(
+		&statementPath, "statement", "s", "statement.json",
+		"Path to the unsigned in-toto statement.",
+	)
+	c.Flags().StringVar(
+		&mode, "mode", string(pkg.PublishAuto),
+		fmt.Sprintf("How to attach the attestation: %q, %q or %q.", pkg.PublishAuto, pkg.PublishReferrers, pkg.PublishCosign),
+	)
+
+	return c
+}
+
+// defaultRemoteOptions returns the registry options used by the CLI.
+func defaultRemoteOptions() []remote.Option {
+	return []remote.Option{remote.WithAuthFromKeychain(authn.DefaultKeychain)}
+}
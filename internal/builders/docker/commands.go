@@ -18,19 +18,23 @@ package main
 // `slsa-container-based-generator` command.
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
-	"github.com/google/go-cmp/cmp"
-	"github.com/google/go-cmp/cmp/cmpopts"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 	"github.com/spf13/cobra"
 
 	"github.com/slsa-framework/slsa-github-generator/internal/builders/docker/pkg"
 	"github.com/slsa-framework/slsa-github-generator/internal/utils"
+	"github.com/slsa-framework/slsa-github-generator/signing"
 )
 
 // DryRunCmd returns a new *cobra.Command that validates the input flags, and
@@ -42,17 +46,17 @@ func DryRunCmd(check func(error)) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "dry-run [FLAGS]",
 		Short: "Generates and stores a JSON-formatted BuildDefinition based on the input arguments.",
-		Run: func(_ *cobra.Command, _ []string) {
+		Run: func(cmd *cobra.Command, _ []string) {
 			w, err := utils.CreateNewFileUnderCurrentDirectory(buildDefinitionPath, os.O_WRONLY)
 			check(err)
 
 			config, err := pkg.NewDockerBuildConfig(inputOptions)
 			check(err)
 
-			builder, err := pkg.NewBuilderWithGitFetcher(config)
+			builder, err := pkg.NewBuilder(config)
 			check(err)
 
-			db, err := builder.SetUpBuildState()
+			db, err := builder.SetUpBuildState(cmd.Context())
 			check(err)
 			// Remove any temporary files that were fetched during the setup.
 			defer db.RepoInfo.Cleanup()
@@ -73,114 +77,390 @@ func DryRunCmd(check func(error)) *cobra.Command {
 func BuildCmd(check func(error)) *cobra.Command {
 	inputOptions := &pkg.InputOptions{}
 	var subjectsPath string
+	var sha256sumPath string
+	var sha256sumBase64 bool
 	var outputFolder string
+	var outputFolderRoots []string
+	var buildMetadataPath string
+	var copyLogs bool
+	var reproducibilityCheck bool
+	var reproducibilityReportPath string
+	var reproducibilityReportFormat string
 
 	cmd := &cobra.Command{
 		Use:   "build [FLAGS]",
 		Short: "Builds the artifacts using the build config, source repo, and the builder image.",
-		Run: func(_ *cobra.Command, _ []string) {
-			// Validate that the output folder is a /tmp subfolder.
-			absoluteOutputFolder, err := filepath.Abs(outputFolder)
+		Run: func(cmd *cobra.Command, _ []string) {
+			// Validate that the output folder is an empty folder in one of
+			// the allowed roots.
+			policy := pkg.OutputFolderPolicy{Roots: outputFolderRoots}
+			absoluteOutputFolder, err := policy.Check(outputFolder)
 			check(err)
-			if !strings.HasPrefix(filepath.Dir(absoluteOutputFolder), "/tmp") {
-				check(fmt.Errorf("output folder must be in /tmp: %s", absoluteOutputFolder))
+
+			if reproducibilityReportFormat != pkg.JSONReportFormat && reproducibilityReportFormat != pkg.MarkdownReportFormat {
+				check(fmt.Errorf("unsupported report format %q, want %q or %q",
+					reproducibilityReportFormat, pkg.JSONReportFormat, pkg.MarkdownReportFormat))
 			}
-			check(pkg.CheckExistingFiles(absoluteOutputFolder))
 
 			w, err := utils.CreateNewFileUnderCurrentDirectory(subjectsPath, os.O_WRONLY)
 			check(err)
+			var sw io.Writer
+			if sha256sumPath != "" {
+				sw, err = utils.CreateNewFileUnderCurrentDirectory(sha256sumPath, os.O_WRONLY)
+				check(err)
+			}
+			var rw io.Writer
+			if reproducibilityCheck && reproducibilityReportPath != "" {
+				rw, err = utils.CreateNewFileUnderCurrentDirectory(reproducibilityReportPath, os.O_WRONLY)
+				check(err)
+			}
 			config, err := pkg.NewDockerBuildConfig(inputOptions)
 			check(err)
+			// Both builds of a reproducibility check use fresh checkouts.
+			if reproducibilityCheck {
+				config.ForceCheckout = true
+			}
 
-			builder, err := pkg.NewBuilderWithGitFetcher(config)
+			builder, err := pkg.NewBuilder(config)
 			check(err)
 
-			db, err := builder.SetUpBuildState()
+			db, err := builder.SetUpBuildState(cmd.Context())
 			check(err)
 			// Remove any temporary files that were generated during the setup.
 			defer db.RepoInfo.Cleanup()
 
 			// Build artifacts and write them to the output folder.
-			artifacts, err := db.BuildArtifacts(absoluteOutputFolder)
+			startedOn := time.Now().UTC()
+			artifacts, err := db.BuildArtifacts(cmd.Context(), absoluteOutputFolder)
 			check(err)
+			finishedOn := time.Now().UTC()
 			check(writeJSONToFile(artifacts, w))
+			if sw != nil {
+				check(pkg.WriteSHA256Sum(sw, artifacts, sha256sumBase64))
+			}
+
+			// The logs of the build steps are recorded as byproducts.
+			logsFolder := ""
+			if copyLogs {
+				logsFolder = absoluteOutputFolder
+			}
+			byproducts, err := db.LogByproducts(logsFolder)
+			check(err)
+
+			if buildMetadataPath != "" {
+				mw, err := utils.CreateNewFileUnderCurrentDirectory(buildMetadataPath, os.O_WRONLY)
+				check(err)
+				check(writeJSONToFile(pkg.BuildRunMetadata{
+					StartedOn:    &startedOn,
+					FinishedOn:   &finishedOn,
+					Byproducts:   byproducts,
+					SubjectPaths: db.SubjectPaths(),
+					// The builder image built from the builder Dockerfile is
+					// only known after the build.
+					ResolvedDependencies: db.BuildDependencies(),
+				}, mw))
+			}
+
+			if reproducibilityCheck {
+				report, err := db.CheckReproducibility(cmd.Context(), artifacts)
+				check(err)
+				if rw != nil {
+					check(report.Write(rw, reproducibilityReportFormat))
+				}
+				check(checkReproducible(report, cmd.ErrOrStderr()))
+			}
 		},
 	}
 
 	inputOptions.AddFlags(cmd)
 	cmd.Flags().StringVarP(&subjectsPath, "subjects-path", "o", "",
 		"Required - Path to store a JSON-encoded array of subjects of the generated artifacts.")
+	cmd.Flags().StringVar(&sha256sumPath, "sha256sum-path", "",
+		"Optional - Path to store the names and SHA256 digests of the generated artifacts in the sha256sum format.")
+	cmd.Flags().BoolVar(&sha256sumBase64, "sha256sum-base64", false,
+		"Optional - Encode the sha256sum file in base64, as expected by the subjects of the generic builder.")
 	cmd.Flags().StringVar(&outputFolder, "output-folder", "",
-		"Required - Path to a folder to store the generated artifacts. MUST be under /tmp.")
+		"Required - Path to an empty or non-existent folder to store the generated artifacts, in one of the "+
+			"output folder roots.")
 	check(cmd.MarkFlagRequired("output-folder"))
+	cmd.Flags().StringArrayVar(&outputFolderRoots, "output-folder-root", pkg.DefaultOutputFolderRoots,
+		"Optional - Absolute path of a directory that the output folder can be in. Can be repeated.")
+	cmd.Flags().StringVar(&buildMetadataPath, "build-metadata-path", "",
+		"Optional - Path to store the JSON-encoded metadata of the build run, used by the provenance command.")
+	cmd.Flags().BoolVar(&copyLogs, "copy-logs", false,
+		"Optional - Copy the logs of the build steps to the build-logs directory of the output folder.")
+	cmd.Flags().BoolVar(&reproducibilityCheck, "reproducibility-check", false,
+		"Optional - Build the artifacts a second time in a fresh checkout, and fail if their digests differ.")
+	cmd.Flags().StringVar(&reproducibilityReportPath, "reproducibility-report-path", "",
+		"Optional - Path to store the report of the reproducibility check to.")
+	cmd.Flags().StringVar(&reproducibilityReportFormat, "reproducibility-report-format", pkg.MarkdownReportFormat,
+		"Optional - Format of the report of the reproducibility check: json or markdown.")
 
 	return cmd
 }
 
+// checkReproducible returns an error listing the non-reproducible artifacts of
+// the report, if any. When running in GitHub Actions, an error annotation is
+// also written to w for each of them.
+func checkReproducible(report *pkg.VerificationReport, w io.Writer) error {
+	names := report.NonReproducible()
+	if len(names) == 0 {
+		return nil
+	}
+	if os.Getenv("GITHUB_ACTIONS") == "true" {
+		for _, name := range names {
+			if _, err := fmt.Fprintf(w, "::error title=Non-reproducible artifact::%s is not reproducible\n", name); err != nil {
+				return err
+			}
+		}
+	}
+	return fmt.Errorf("the artifacts are not reproducible: %s", strings.Join(names, ", "))
+}
+
 // VerifyCmd returns a new *cobra.Command that takes a provenance file, and
 // verifies it by running the build steps and comparing the generated artifacts
-// to the subject of the provenance file.
+// to the subject of the provenance file. The signature of the provenance is
+// verified before running any build step. It writes a report of the
+// comparison, and terminates with an error if the artifacts do not match.
 func VerifyCmd(check func(error)) *cobra.Command {
 	var provenancePath string
+	var containerRuntime string
+	var builderImageLayout string
+	var reportPath string
+	var reportFormat string
+	var outputFolder string
+	var identityRegexp string
+	var oidcIssuer string
+	var trustedRootPath string
+	var insecureUnsigned bool
+	var secretSpecs []string
 
 	cmd := &cobra.Command{
 		Use:   "verify [FLAGS]",
 		Short: "Verifies as SLSLv1.0 provenance.",
-		Run: func(_ *cobra.Command, _ []string) {
-			err := verifyProvenance(provenancePath)
+		Run: func(cmd *cobra.Command, _ []string) {
+			if reportFormat != pkg.JSONReportFormat && reportFormat != pkg.MarkdownReportFormat {
+				check(fmt.Errorf("unsupported report format %q, want %q or %q",
+					reportFormat, pkg.JSONReportFormat, pkg.MarkdownReportFormat))
+			}
+
+			w := cmd.OutOrStdout()
+			if reportPath != "" {
+				f, err := utils.CreateNewFileUnderCurrentDirectory(reportPath, os.O_WRONLY)
+				check(err)
+				w = f
+			}
+
+			if outputFolder != "" {
+				var err error
+				outputFolder, err = filepath.Abs(outputFolder)
+				check(err)
+				check(pkg.CheckExistingFiles(outputFolder))
+			}
+
+			if builderImageLayout != "" {
+				var err error
+				builderImageLayout, err = filepath.Abs(builderImageLayout)
+				check(err)
+			}
+
+			// The secrets required by the build config in the provenance are
+			// only passed to the rebuild if they are explicitly supplied.
+			secrets, err := pkg.ParseSecrets(secretSpecs)
+			check(err)
+
+			verifier, err := pkg.NewProvenanceVerifier(identityRegexp, oidcIssuer, trustedRootPath, insecureUnsigned)
 			check(err)
+
+			report, err := verifyProvenance(cmd.Context(), provenancePath, verifier, containerRuntime,
+				builderImageLayout, outputFolder, secrets)
+			check(err)
+			check(report.Write(w, reportFormat))
+			if !report.Reproducible {
+				check(errors.New("the rebuilt artifacts do not match the subjects of the provenance"))
+			}
 		},
 	}
 
 	cmd.Flags().StringVarP(&provenancePath, "provenance-path", "o", "",
 		"Required - Path to the input provenance file.")
+	cmd.Flags().StringVar(&containerRuntime, "container-runtime", pkg.DockerRuntime,
+		"Optional - Container runtime used to run the build: docker, podman or nerdctl.")
+	cmd.Flags().StringVar(&builderImageLayout, "builder-image-layout", "",
+		"Optional - Path to an OCI image layout directory or tarball to load the builder images from.")
+	cmd.Flags().StringVar(&reportPath, "report-path", "",
+		"Optional - Path to store the verification report to. The report is printed to stdout by default.")
+	cmd.Flags().StringVar(&reportFormat, "report-format", pkg.MarkdownReportFormat,
+		"Optional - Format of the verification report: json or markdown.")
+	cmd.Flags().StringVar(&outputFolder, "output-folder", "",
+		"Optional - Path to a folder to store the rebuilt artifacts, e.g., for comparing them using diffoscope.")
+	cmd.Flags().StringVar(&identityRegexp, "builder-identity-regexp", pkg.DefaultBuilderIdentityRegexp,
+		"Optional - Regular expression matching the identity of the builder in the signing certificate.")
+	cmd.Flags().StringVar(&oidcIssuer, "builder-oidc-issuer", pkg.DefaultBuilderOIDCIssuer,
+		"Optional - OIDC issuer of the identity of the builder in the signing certificate.")
+	cmd.Flags().StringVar(&trustedRootPath, "trusted-root", "",
+		"Optional - Path to a Sigstore trusted root. The public-good trusted root is fetched by default.")
+	cmd.Flags().BoolVar(&insecureUnsigned, "insecure-unsigned", false,
+		"Optional - Accept an unsigned provenance, or a DSSE envelope without a transparency log entry or signed timestamp. "+
+			"The build steps in the provenance are run without checking who created it.")
+	cmd.Flags().StringArrayVar(&secretSpecs, "secret", nil,
+		"Optional - Secret required by the build config in the provenance, as id=NAME,src=PATH or id=NAME,env=VARIABLE. "+
+			"Can be repeated.")
 
 	return cmd
 }
 
-func verifyProvenance(provenancePath string) error {
+// verifyProvenance verifies the signature of the provenance, rebuilds its
+// subjects, and returns a report comparing them to the rebuilt artifacts. The
+// rebuilt artifacts are written to outputFolder, if it is not empty. The
+// builder images are loaded from builderImageLayout, if it is not empty. The
+// secrets are passed to the rebuild, and must be the ones required by the
+// build config.
+func verifyProvenance(ctx context.Context, provenancePath string, verifier *pkg.ProvenanceVerifier,
+	containerRuntime, builderImageLayout, outputFolder string, secrets []pkg.Secret,
+) (*pkg.VerificationReport, error) {
 	// Note: We can use os.ReadFile here directly without checking for directory
 	// traversal. This is a verification tool, and not used by the build
 	// workflows.
 	bytes, err := os.ReadFile(provenancePath)
 	if err != nil {
-		return fmt.Errorf("reading provenance file: %w", err)
+		return nil, fmt.Errorf("reading provenance file: %w", err)
 	}
 
-	provenance, err := pkg.ParseProvenance(bytes)
+	// The provenance contains the builder image and the command to run, so it
+	// must come from the expected builder before anything is executed.
+	statement, err := verifier.Verify(bytes)
 	if err != nil {
-		return fmt.Errorf("parsing provenance file: %w", err)
+		return nil, fmt.Errorf("verifying the provenance signature: %w", err)
+	}
+
+	provenance, err := pkg.ParseProvenance(statement)
+	if err != nil {
+		return nil, fmt.Errorf("parsing provenance file: %w", err)
 	}
 
 	config, err := provenance.ToDockerBuildConfig(true)
 	if err != nil {
-		return fmt.Errorf("creating DockerBuildConfig from provenance: %w", err)
+		return nil, fmt.Errorf("creating DockerBuildConfig from provenance: %w", err)
 	}
+	config.ContainerRuntime = containerRuntime
+	config.BuilderImageLayout = builderImageLayout
+	config.Secrets = secrets
 
-	builder, err := pkg.NewBuilderWithGitFetcher(config)
+	builder, err := pkg.NewBuilder(config)
 	if err != nil {
-		return fmt.Errorf("creating BuilderWithGitFetcher: %w", err)
+		return nil, fmt.Errorf("creating Builder: %w", err)
 	}
 
-	db, err := builder.SetUpBuildState()
+	db, err := builder.SetUpBuildState(ctx)
 	if err != nil {
-		return fmt.Errorf("setting up the build state: %w", err)
+		return nil, fmt.Errorf("setting up the build state: %w", err)
 	}
 	// Remove any temporary files that were fetched during the setup.
 	defer db.RepoInfo.Cleanup()
 
+	// The build config loaded from the source repository must match the one
+	// recorded in the provenance, so that the build is replayed exactly.
+	if err := provenance.CheckExternalParameters(db.CreateBuildDefinition().ExternalParameters); err != nil {
+		return nil, err
+	}
+
 	// Build artifacts and get their digests.
-	artifacts, err := db.BuildArtifacts("")
+	artifacts, err := db.BuildArtifacts(ctx, outputFolder)
 	if err != nil {
-		return fmt.Errorf("building the artifacts: %w", err)
+		return nil, fmt.Errorf("building the artifacts: %w", err)
 	}
 
-	less := func(a, b string) bool { return a < b }
-	diff := cmp.Diff(artifacts, provenance.Subject, cmpopts.SortSlices(less))
-	if diff != "" {
-		return fmt.Errorf("comparing the subjects artifacts: %w", err)
+	return pkg.NewVerificationReport(provenance, artifacts), nil
+}
+
+// ProvenanceCmd returns a new *cobra.Command that generates a SLSA v1.0
+// provenance from the outputs of the dry-run and build commands, and signs it
+// using the signer, or terminates with an error.
+func ProvenanceCmd(check func(error), signer signing.Signer) *cobra.Command {
+	var buildDefinitionPath string
+	var subjectsPath string
+	var buildMetadataPath string
+	var byproductPaths []string
+	var runDetailsOpts pkg.RunDetailsOptions
+	var outputPath string
+	var unsigned bool
+
+	cmd := &cobra.Command{
+		Use:   "provenance [FLAGS]",
+		Short: "Generates and signs a SLSA v1.0 provenance for the artifacts built by the build command.",
+		Run: func(cmd *cobra.Command, _ []string) {
+			var bd slsa1.ProvenanceBuildDefinition
+			check(readJSONFromFile(buildDefinitionPath, &bd))
+
+			var subjects []intoto.Subject
+			check(readJSONFromFile(subjectsPath, &subjects))
+
+			var metadata *pkg.BuildRunMetadata
+			if buildMetadataPath != "" {
+				metadata = &pkg.BuildRunMetadata{}
+				check(readJSONFromFile(buildMetadataPath, metadata))
+				check(pkg.RecordSubjectPaths(&bd, metadata.SubjectPaths))
+				pkg.RecordResolvedDependencies(&bd, metadata.ResolvedDependencies)
+			}
+
+			rd, err := pkg.NewRunDetails(runDetailsOpts, metadata)
+			check(err)
+			for _, path := range byproductPaths {
+				b, err := pkg.FileByproduct(path)
+				check(err)
+				rd.Byproducts = append(rd.Byproducts, *b)
+			}
+
+			statement, err := pkg.NewProvenanceStatement(subjects, &bd, rd)
+			check(err)
+
+			w, err := utils.CreateNewFileUnderCurrentDirectory(outputPath, os.O_WRONLY)
+			check(err)
+			if unsigned {
+				check(writeJSONToFile(statement, w))
+				return
+			}
+			att, err := pkg.SignProvenance(cmd.Context(), signer, statement)
+			check(err)
+			_, err = w.Write(att)
+			check(err)
+		},
 	}
 
+	cmd.Flags().StringVar(&buildDefinitionPath, "build-definition-path", "",
+		"Required - Path to the BuildDefinition generated by the dry-run command.")
+	cmd.Flags().StringVar(&subjectsPath, "subjects-path", "",
+		"Required - Path to the JSON-encoded array of subjects generated by the build command.")
+	cmd.Flags().StringVar(&buildMetadataPath, "build-metadata-path", "",
+		"Optional - Path to the metadata of the build run generated by the build command.")
+	cmd.Flags().StringVar(&runDetailsOpts.BuilderID, "builder-id", "",
+		"Required - ID of the builder, e.g., the URI of the reusable workflow at a ref.")
+	cmd.Flags().StringVar(&runDetailsOpts.InvocationID, "invocation-id", "",
+		"Optional - ID of the build invocation, e.g., the URL of the workflow run.")
+	cmd.Flags().StringArrayVar(&byproductPaths, "byproduct", nil,
+		"Optional - Path to a file, such as a log, to record as a byproduct of the build. Can be repeated.")
+	cmd.Flags().StringVarP(&outputPath, "output-path", "o", "",
+		"Required - Path to store the signed provenance to.")
+	cmd.Flags().BoolVar(&unsigned, "unsigned", false,
+		"Optional - Store the unsigned in-toto statement instead of signing it.")
+	for _, name := range []string{"build-definition-path", "subjects-path", "builder-id", "output-path"} {
+		check(cmd.MarkFlagRequired(name))
+	}
+
+	return cmd
+}
+
+// readJSONFromFile reads the JSON-encoded object from the file, which must be
+// under the current directory.
+func readJSONFromFile(path string, obj any) error {
+	bytes, err := utils.SafeReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading %q: %w", path, err)
+	}
+	if err := json.Unmarshal(bytes, obj); err != nil {
+		return fmt.Errorf("unmarshaling %q: %w", path, err)
+	}
 	return nil
 }
 
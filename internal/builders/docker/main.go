@@ -15,11 +15,16 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+
+	"github.com/slsa-framework/slsa-github-generator/signing/sigstore"
 )
 
 func checkExit(err error) {
@@ -42,9 +47,14 @@ For more information on SLSA, visit https://slsa.dev`,
 	cmd.AddCommand(DryRunCmd(checkExit))
 	cmd.AddCommand(BuildCmd(checkExit))
 	cmd.AddCommand(VerifyCmd(checkExit))
+	cmd.AddCommand(ProvenanceCmd(checkExit, sigstore.NewDefaultBundleSigner()))
 	return cmd
 }
 
 func main() {
-	checkExit(rootCmd().Execute())
+	// The running build step is stopped when the command is interrupted.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := rootCmd().ExecuteContext(ctx)
+	stop()
+	checkExit(err)
 }
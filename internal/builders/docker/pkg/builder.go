@@ -24,7 +24,8 @@ package pkg
 
 import (
 	"bufio"
-	"crypto/sha256"
+	"context"
+	"crypto/rand"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -38,6 +39,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
@@ -45,6 +47,10 @@ import (
 	"github.com/slsa-framework/slsa-github-generator/internal/utils"
 )
 
+// workspaceDir is the directory in the container where the source repository
+// is mounted.
+const workspaceDir = "/workspace"
+
 var (
 	// errGitCommitMismatch indicates that the repo is checked out at an unexpected commit hash.
 	errGitCommitMismatch = errors.New("commit mismatch")
@@ -54,6 +60,12 @@ var (
 
 	// errGitCheckout indicates an error when checking out a given commit hash.
 	errGitCheckout = errors.New("git checkout")
+
+	// errSubjectCollision indicates that two artifacts have the same subject name.
+	errSubjectCollision = errors.New("subject name collision")
+
+	// errBuildTimeout indicates that the build did not complete within its timeout.
+	errBuildTimeout = errors.New("build timeout")
 )
 
 // DockerBuild represents a state in the process of building the artifacts
@@ -62,7 +74,33 @@ var (
 type DockerBuild struct {
 	config      *DockerBuildConfig
 	buildConfig *BuildConfig
-	RepoInfo    *RepoCheckoutInfo
+	runtime     ContainerRuntime
+	runtimeInfo *RuntimeInfo
+	// Mapping from subject names to paths, set by BuildArtifacts.
+	subjectPaths map[string]string
+	// Logs of the build steps run by BuildArtifacts.
+	logs []buildLog
+	// Directory in the container where the source repository is mounted.
+	// workspaceDir is used if it is empty.
+	workspace string
+	// Time set as SOURCE_DATE_EPOCH in the build steps that do not set it,
+	// unless it is zero.
+	sourceDateEpoch time.Time
+	// Base images of the builder Dockerfile, if the build config sets one.
+	baseImages []DockerImage
+	// Builder image built from the builder Dockerfile by BuildArtifacts.
+	builtImage *slsa1.ResourceDescriptor
+	// Flags of `docker run` mounting the secrets, set by BuildArtifacts.
+	secretMounts []string
+	RepoInfo     *RepoCheckoutInfo
+}
+
+// buildLog is a log file of a build step.
+type buildLog struct {
+	// Name of the log in the provenance, e.g., `step-1.stdout.log`.
+	name string
+	// Path of the temporary file containing the log.
+	path string
 }
 
 // RepoCheckoutInfo contains info about the location of a locally checked out
@@ -70,6 +108,9 @@ type DockerBuild struct {
 type RepoCheckoutInfo struct {
 	// Path to the root of the repo.
 	RepoRoot string
+
+	// Submodules of the repo, which are recorded as resolved dependencies.
+	Submodules []Submodule
 }
 
 // Fetcher is an interface with a single method Fetch, for fetching a
@@ -82,9 +123,21 @@ type Fetcher interface {
 // commands to build artifacts as specified in a DockerBuildConfig.
 type Builder struct {
 	repoFetcher Fetcher
+	runtime     ContainerRuntime
 	config      DockerBuildConfig
 }
 
+// NewBuilder creates a new Builder that fetches the sources using the Fetcher
+// for the source repository: a remote Git repository, or a local tarball, Git
+// bundle, or directory for `file` URIs.
+func NewBuilder(config *DockerBuildConfig) (*Builder, error) {
+	f, err := newFetcher(config)
+	if err != nil {
+		return nil, fmt.Errorf("could not create builder: %w", err)
+	}
+	return newBuilder(config, f)
+}
+
 // NewBuilderWithGitFetcher creates a new Builder that fetches the sources
 // from a Git repository.
 func NewBuilderWithGitFetcher(config *DockerBuildConfig) (*Builder, error) {
@@ -92,9 +145,18 @@ func NewBuilderWithGitFetcher(config *DockerBuildConfig) (*Builder, error) {
 	if err != nil {
 		return nil, fmt.Errorf("could not create builder: %v", err)
 	}
+	return newBuilder(config, gc)
+}
+
+func newBuilder(config *DockerBuildConfig, f Fetcher) (*Builder, error) {
+	rt, err := NewContainerRuntime(config.ContainerRuntime)
+	if err != nil {
+		return nil, fmt.Errorf("could not create builder: %w", err)
+	}
 
 	return &Builder{
-		repoFetcher: gc,
+		repoFetcher: f,
+		runtime:     rt,
 		config:      *config,
 	}, nil
 }
@@ -109,13 +171,67 @@ func (db *DockerBuild) CreateBuildDefinition() *slsa1.ProvenanceBuildDefinition
 		Config:       *db.buildConfig,
 	}
 
+	// The source repository is also added as a resolved dependency, followed
+	// by its submodules, the builder images of the steps that do not use the
+	// main one, and the base images of the builder Dockerfile.
+	deps := []slsa1.ResourceDescriptor{sourceArtifact(db.config)}
+	if db.RepoInfo != nil {
+		for _, s := range db.RepoInfo.Submodules {
+			deps = append(deps, s.resourceDescriptor())
+		}
+	}
+	deps = append(deps, stepImages(db.config, db.buildConfig)...)
+	for _, di := range db.baseImages {
+		deps = append(deps, slsa1.ResourceDescriptor{
+			URI:    di.ToString(),
+			Digest: di.Digest.ToMap(),
+		})
+	}
+
 	// Currently we don't have any SystemParameters, so this fields is left empty.
-	return &slsa1.ProvenanceBuildDefinition{
-		BuildType:          ContainerBasedBuildType,
-		ExternalParameters: ep,
-		// The source repository is also added as a resolved dependency.
-		ResolvedDependencies: []slsa1.ResourceDescriptor{sourceArtifact(db.config)},
+	bd := &slsa1.ProvenanceBuildDefinition{
+		BuildType:            ContainerBasedBuildType,
+		ExternalParameters:   ep,
+		ResolvedDependencies: deps,
+	}
+	internal := map[string]any{}
+	if db.runtimeInfo != nil {
+		internal[ContainerRuntimeKey] = *db.runtimeInfo
+	}
+	if db.buildConfig.Hermetic {
+		internal[HermeticKey] = true
+	}
+	if len(internal) > 0 {
+		bd.InternalParameters = internal
 	}
+	return bd
+}
+
+// stepImages returns the distinct builder images of the prefetch and build
+// steps that differ from the builder image of the build, as ResourceDescriptors. The
+// images have been validated when loading the build config.
+func stepImages(config *DockerBuildConfig, bc *BuildConfig) []slsa1.ResourceDescriptor {
+	seen := map[string]bool{config.BuilderImage.ToString(): true}
+	var images []slsa1.ResourceDescriptor
+	steps := bc.Steps
+	if bc.Prefetch != nil {
+		steps = append([]BuildStep{*bc.Prefetch}, steps...)
+	}
+	for _, step := range steps {
+		if step.BuilderImage == "" || seen[step.BuilderImage] {
+			continue
+		}
+		seen[step.BuilderImage] = true
+		di, err := validateDockerImage(step.BuilderImage)
+		if err != nil {
+			continue
+		}
+		images = append(images, slsa1.ResourceDescriptor{
+			URI:    di.ToString(),
+			Digest: di.Digest.ToMap(),
+		})
+	}
+	return images
 }
 
 // sourceArtifact returns the source repo and its digest as an instance of ResourceDescriptor.
@@ -128,6 +244,10 @@ func sourceArtifact(config *DockerBuildConfig) slsa1.ResourceDescriptor {
 
 // builderImage returns the builder image as an instance of ResourceDescriptor.
 func builderImage(config *DockerBuildConfig) slsa1.ResourceDescriptor {
+	// The builder image is built from the builder Dockerfile instead.
+	if !config.BuilderImage.isSet() {
+		return slsa1.ResourceDescriptor{}
+	}
 	return slsa1.ResourceDescriptor{
 		URI:    config.BuilderImage.ToString(),
 		Digest: config.BuilderImage.Digest.ToMap(),
@@ -136,8 +256,9 @@ func builderImage(config *DockerBuildConfig) slsa1.ResourceDescriptor {
 
 // SetUpBuildState sets up the build by checking out the source repository and
 // loading the config file. It returns an instance of DockerBuild, or an error
-// if setting up the build state fails.
-func (b *Builder) SetUpBuildState() (*DockerBuild, error) {
+// if setting up the build state fails. The commands of the container runtime
+// are stopped if ctx is done.
+func (b *Builder) SetUpBuildState(ctx context.Context) (*DockerBuild, error) {
 	// 1. Check out the repo, or verify that it is checked out.
 	repoInfo, err := b.repoFetcher.Fetch()
 	if err != nil {
@@ -149,57 +270,300 @@ func (b *Builder) SetUpBuildState() (*DockerBuild, error) {
 	if err != nil {
 		return nil, fmt.Errorf("couldn't load config file from %q: %v", b.config.BuildConfigPath, err)
 	}
+	if err := bc.overrideResourceLimits(b.config.Limits); err != nil {
+		return nil, fmt.Errorf("invalid resource limits: %w", err)
+	}
+
+	// 3. Check that the artifact patterns do not match any existing files, so
+	// that we don't accidentally generate provenances for the wrong files.
+	if err := checkExistingArtifacts(bc); err != nil {
+		return nil, err
+	}
 
-	// 3. Check that the ArtifactPath pattern does not match any existing files,
-	// so that we don't accidentally generate provenances for the wrong files.
-	if err := CheckExistingFiles(bc.ArtifactPath); err != nil {
+	// 4. Check the builder image, or the base images of the Dockerfile it is
+	// built from, which are recorded as resolved dependencies, and the
+	// secrets of the build.
+	if err := checkBuilderImage(&b.config, bc); err != nil {
 		return nil, err
 	}
+	if err := checkSecrets(bc.Secrets, b.config.Secrets); err != nil {
+		return nil, err
+	}
+	var baseImages []DockerImage
+	if bc.BuilderDockerfile != "" {
+		if err := utils.PathIsUnderCurrentDirectory(bc.BuilderDockerfile); err != nil {
+			return nil, fmt.Errorf("%w: %w", errBuilderDockerfile, err)
+		}
+		if baseImages, err = dockerfileBaseImages(bc.BuilderDockerfile); err != nil {
+			return nil, err
+		}
+	}
+
+	// 5. Set up the container runtime.
+	rt := b.runtime
+	if rt == nil {
+		if rt, err = NewContainerRuntime(b.config.ContainerRuntime); err != nil {
+			return nil, err
+		}
+	}
 
 	db := &DockerBuild{
 		config:      &b.config,
 		buildConfig: bc,
+		runtime:     rt,
+		runtimeInfo: runtimeInfo(ctx, rt),
+		baseImages:  baseImages,
 		RepoInfo:    repoInfo,
 	}
 	return db, nil
 }
 
 // BuildArtifacts builds the artifacts based on the user-provided inputs, and
-// returns the names and SHA256 digests of the generated artifacts.
-func (db *DockerBuild) BuildArtifacts(outputFolder string) ([]intoto.Subject, error) {
-	if err := runDockerRun(db); err != nil {
-		return nil, fmt.Errorf("running `docker run` failed: %v", err)
+// returns the names and SHA256 digests of the generated artifacts. The running
+// build step is stopped if ctx is done before the build completes.
+func (db *DockerBuild) BuildArtifacts(ctx context.Context, outputFolder string) ([]intoto.Subject, error) {
+	if err := runDockerRun(ctx, db); err != nil {
+		return nil, fmt.Errorf("running `docker run` failed: %w", err)
+	}
+	subjects, paths, err := inspectAndWriteArtifacts(db.buildConfig.ArtifactPatterns(),
+		db.buildConfig.ArtifactExcludes, outputFolder, db.RepoInfo.RepoRoot, db.buildConfig.PreservePaths)
+	if err != nil {
+		return nil, err
 	}
-	return inspectAndWriteArtifacts(db.buildConfig.ArtifactPath, outputFolder, db.RepoInfo.RepoRoot)
+	db.subjectPaths = paths
+	return subjects, nil
 }
 
-func runDockerRun(db *DockerBuild) error {
+// SubjectPaths returns the mapping from the names of the subjects built by
+// BuildArtifacts to their paths relative to the root of the source
+// repository.
+func (db *DockerBuild) SubjectPaths() map[string]string {
+	return db.subjectPaths
+}
+
+// LogByproducts returns the logs of the build steps run by BuildArtifacts, with
+// their SHA256 digests, for recording them as byproducts of the build. The
+// logs are also copied to the `build-logs` directory of outputFolder, if it is
+// not empty.
+func (db *DockerBuild) LogByproducts(outputFolder string) ([]slsa1.ResourceDescriptor, error) {
+	byproducts := make([]slsa1.ResourceDescriptor, 0, len(db.logs))
+	for _, l := range db.logs {
+		digest, err := copyLog(l, outputFolder)
+		if err != nil {
+			return nil, fmt.Errorf("recording the log %q: %w", l.name, err)
+		}
+		byproducts = append(byproducts, slsa1.ResourceDescriptor{
+			Name:      l.name,
+			Digest:    digest,
+			MediaType: "text/plain",
+		})
+	}
+	return byproducts, nil
+}
+
+// buildLogsDir is the directory of the output folder the logs are copied to.
+const buildLogsDir = "build-logs"
+
+// copyLog hashes the log, and copies it to outputFolder if it is not empty.
+func copyLog(l buildLog, outputFolder string) (map[string]string, error) {
+	f, err := os.Open(l.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if outputFolder == "" {
+		return utils.HashReader(nil, f)
+	}
+	out, err := utils.CreateNewFileUnderDirectory(path.Join(buildLogsDir, l.name), outputFolder, os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+	digest, err := utils.HashReader(out, f)
+	if cerr := out.(io.Closer).Close(); err == nil && cerr != nil {
+		err = cerr
+	}
+	return digest, err
+}
+
+func runDockerRun(ctx context.Context, db *DockerBuild) error {
 	// Get the current working directory. We will mount it as a Docker volume.
 	cwd, err := os.Getwd()
 	if err != nil {
 		return fmt.Errorf("couldn't get the current working directory: %v", err)
 	}
 
+	buildDef := db.CreateBuildDefinition()
+	containerEp, ok := buildDef.ExternalParameters.(ContainerBasedExternalParameters)
+	if !ok {
+		return fmt.Errorf("expected container-based external parameters")
+	}
+
+	// The timeout also applies to pulling and loading the images.
+	limits, err := db.buildConfig.resourceLimits()
+	if err != nil {
+		return err
+	}
+	if limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, limits.Timeout,
+			fmt.Errorf("%w: the build did not complete within %v", errBuildTimeout, limits.Timeout))
+		defer cancel()
+	}
+
+	rt := db.containerRuntime()
+	var layout *ociLayout
+	if db.config.BuilderImageLayout != "" {
+		var cleanup func()
+		layout, cleanup, err = openOCILayout(db.config.BuilderImageLayout)
+		defer cleanup()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The images of all the steps, and the base images of the builder
+	// Dockerfile, are resolved to local images, whose digests are verified,
+	// before running any step. The steps are then run using the IDs of the
+	// local images.
+	resolver := newImageResolver(rt, layout)
+	for _, di := range db.baseImages {
+		if _, err := resolver.resolve(ctx, di.ToString()); err != nil {
+			return canceled(ctx, err)
+		}
+	}
+	images := map[*BuildStep]string{}
+	steps := db.buildConfig.BuildSteps()
+	all := make([]*BuildStep, 0, len(steps)+1)
+	if db.buildConfig.Prefetch != nil {
+		all = append(all, db.buildConfig.Prefetch)
+	}
+	for i := range steps {
+		all = append(all, &steps[i])
+	}
+	for _, step := range all {
+		image := containerEp.BuilderImage.URI
+		if step.BuilderImage != "" {
+			image = step.BuilderImage
+		}
+		// The image built from the builder Dockerfile is used instead.
+		if image == "" {
+			continue
+		}
+		if images[step], err = resolver.resolve(ctx, image); err != nil {
+			return canceled(ctx, err)
+		}
+	}
+	stepImage := func(step *BuildStep) string {
+		return images[step]
+	}
+
+	// The secrets are removed from the host once the build completes.
+	mounts, cleanup, err := mountSecrets(db.config.Secrets, secretsTempDir)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	db.secretMounts = mounts
+
+	if dockerfile := db.buildConfig.BuilderDockerfile; dockerfile != "" {
+		id, err := buildBuilderImage(ctx, db, rt, dockerfile)
+		if err != nil {
+			return err
+		}
+		for _, step := range all {
+			if _, ok := images[step]; !ok {
+				images[step] = id
+			}
+		}
+	}
+
+	// The dependencies of hermetic builds are fetched with network access
+	// before running the isolated build steps.
+	if prefetch := db.buildConfig.Prefetch; prefetch != nil {
+		log.Printf("Running the prefetch step.")
+		if err := runDockerRunStep(ctx, db, cwd, "prefetch", stepImage(prefetch), prefetch, false, &limits); err != nil {
+			return fmt.Errorf("prefetch: %w", err)
+		}
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		if len(steps) > 1 {
+			log.Printf("Running step %d of %d.", i+1, len(steps))
+		}
+		name := fmt.Sprintf("step-%d", i+1)
+		if err := runDockerRunStep(ctx, db, cwd, name, stepImage(step), step, db.buildConfig.Hermetic, &limits); err != nil {
+			if len(steps) > 1 {
+				return fmt.Errorf("step %d: %w", i+1, err)
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// canceled returns err, wrapped with the cause of the cancellation of ctx if
+// it is done, e.g., errBuildTimeout.
+func canceled(ctx context.Context, err error) error {
+	if ctx.Err() == nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", context.Cause(ctx), err)
+}
+
+// containerName returns a random name for the container of a build step, so
+// that it can be removed if the build is canceled.
+func containerName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("couldn't generate the container name: %v", err)
+	}
+	return "slsa-build-" + hex.EncodeToString(b), nil
+}
+
+// containerRuntime returns the container runtime of the build, which defaults
+// to Docker.
+func (db *DockerBuild) containerRuntime() ContainerRuntime {
+	if db.runtime == nil {
+		return &cliRuntime{name: DockerRuntime}
+	}
+	return db.runtime
+}
+
+// runDockerRunStep runs the build step in a container from the image. The
+// logs of the step are recorded in db, using the given name.
+func runDockerRunStep(ctx context.Context, db *DockerBuild, cwd, name, image string, step *BuildStep, isolated bool,
+	limits *ResourceLimits,
+) error {
+	workspace := db.workspace
+	if workspace == "" {
+		workspace = workspaceDir
+	}
 	defaultDockerRunFlags := []string{
 		// Mount the current working directory to workspace.
-		fmt.Sprintf("--volume=%s:/workspace", cwd),
-		"--workdir=/workspace",
+		fmt.Sprintf("--volume=%s:%s", cwd, workspace),
+		"--workdir=" + workspace,
 		// Remove the container file system after the container exits.
 		"--rm",
 	}
 
-	buildDef := db.CreateBuildDefinition()
-	containerEp, ok := buildDef.ExternalParameters.(ContainerBasedExternalParameters)
-	if !ok {
-		return fmt.Errorf("expected container-based external parameters")
-	}
+	rt := db.containerRuntime()
 
-	var args []string
-	args = append(args, "run")
-	args = append(args, defaultDockerRunFlags...)
-	args = append(args, containerEp.BuilderImage.URI)
-	args = append(args, db.buildConfig.Command...)
-	cmd := exec.Command("docker", args...)
+	var flags []string
+	flags = append(flags, defaultDockerRunFlags...)
+	flags = append(flags, db.buildConfig.dockerRunOptions(step, isolated)...)
+	if !db.sourceDateEpoch.IsZero() && !db.buildConfig.setsEnv(step, sourceDateEpochEnv) {
+		flags = append(flags, fmt.Sprintf("--env=%s=%d", sourceDateEpochEnv, db.sourceDateEpoch.Unix()))
+	}
+	flags = append(flags, limits.dockerRunFlags()...)
+	flags = append(flags, db.secretMounts...)
+	container, err := containerName()
+	if err != nil {
+		return err
+	}
+	cmd := rt.RunCommand(ctx, container, flags, image, step.Command)
 
 	log.Printf("Running command: %q.", cmd.String())
 
@@ -213,15 +577,21 @@ func runDockerRun(db *DockerBuild) error {
 	}
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("couldn't start the 'git checkout' command: %v", err)
+		return fmt.Errorf("couldn't start the '%s run' command: %v", rt.Name(), err)
 	}
 
 	files, err := saveToTempFile(db.config.Verbose, stdout, stderr)
 	if err != nil {
 		return fmt.Errorf("cannot save logs and errs to file: %v", err)
 	}
+	db.logs = append(db.logs,
+		buildLog{name: name + ".stdout.log", path: files[0]},
+		buildLog{name: name + ".stderr.log", path: files[1]})
 
 	if err := cmd.Wait(); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w; see %s for logs, and %s for errors", context.Cause(ctx), files[0], files[1])
+		}
 		return fmt.Errorf("failed to complete the command: %v; see %s for logs, and %s for errors",
 			err, files[0], files[1])
 	}
@@ -301,6 +671,11 @@ func (c *GitClient) Fetch() (*RepoCheckoutInfo, error) {
 	if err := c.verifyOrFetchRepo(); err != nil {
 		return nil, err
 	}
+	submodules, err := listSubmodules()
+	if err != nil {
+		return nil, err
+	}
+	c.checkoutInfo.Submodules = submodules
 	return c.checkoutInfo, nil
 }
 
@@ -324,9 +699,11 @@ func (c *GitClient) verifyOrFetchRepo() error {
 
 // verifyRefAndCommit checks that the current working directory is the root of a Git
 // repository at the given commit hash. If a source ref is also specified, verifies
-// that the ref resolves to the given commit hash.
+// that the ref resolves to the given commit hash. Also initializes the submodules
+// that are not initialized, and verifies that the submodules are checked out at
+// their pinned commits, and that the Git LFS objects are checked out.
 // Returns an error if the working directory is a Git repository at a different commit
-// or ref.
+// or ref, or if its submodules or LFS objects are not checked out.
 func (c *GitClient) verifyRefAndCommit() (bool, error) {
 	checkCmds := []*exec.Cmd{exec.Command("git", "rev-parse", "--verify", "HEAD")}
 	if c.sourceRef != nil {
@@ -349,6 +726,13 @@ func (c *GitClient) verifyRefAndCommit() (bool, error) {
 		}
 	}
 
+	if err := c.initSubmodules(); err != nil {
+		return false, err
+	}
+	if err := verifySubmodulesAndLFS(); err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
 
@@ -381,7 +765,7 @@ func (c *GitClient) fetchSourcesFromGitRepo() error {
 	}
 
 	// Change directory to the root of the cloned repo.
-	repoName := path.Base(*c.sourceRepo)
+	repoName := cloneDirName(*c.sourceRepo)
 	if err := os.Chdir(repoName); err != nil {
 		return fmt.Errorf("couldn't change directory to %q: %v", repoName, err)
 	}
@@ -401,49 +785,50 @@ func (c *GitClient) fetchSourcesFromGitRepo() error {
 	return nil
 }
 
+// cloneDirName returns the name of the directory that `git clone` clones the
+// repo into, i.e., the base name of the repo without the `.git` or `.bundle`
+// extension.
+func cloneDirName(repo string) string {
+	name := path.Base(strings.TrimSuffix(repo, "/"))
+	name = strings.TrimSuffix(name, gitBundleExtension)
+	return strings.TrimSuffix(name, ".git")
+}
+
 // Clones a Git repo from the URI in this GitClient, up to the depth given in
 // this GitClient. If depth is 0 or negative, the entire repo is cloned.
 func (c *GitClient) cloneGitRepo() error {
-	//#nosec G204 -- Input from user config file.
-	cmd := exec.Command("git", "clone", *c.sourceRepo)
+	args := []string{"clone", *c.sourceRepo}
 	if c.depth > 0 {
-		//#nosec G204 -- Input from user config file.
-		cmd = exec.Command("git", "clone", "--depth", fmt.Sprintf("%d", c.depth), *c.sourceRepo)
+		args = []string{"clone", "--depth", fmt.Sprintf("%d", c.depth), *c.sourceRepo}
 	}
 	log.Printf("Cloning the repo from %s...", *c.sourceRepo)
+	return c.runGitCommand(args...)
+}
 
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		return fmt.Errorf("couldn't get the command's stdout: %v", err)
-	}
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		return fmt.Errorf("couldn't get the command's stderr: %v", err)
-	}
-
-	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("couldn't start the 'git checkout' command: %v", err)
+func (c *GitClient) checkoutGitCommit() error {
+	if err := c.runGitCommand("checkout", c.sourceDigest.Value); err != nil {
+		return err
 	}
 
-	files, err := saveToTempFile(c.verbose, stdout, stderr)
-	if err != nil {
-		return fmt.Errorf("cannot save logs and errs to file: %v", err)
+	// The submodules and LFS objects are part of the sources, so they must be
+	// fetched before verifying the checkout.
+	if err := c.fetchSubmodulesAndLFS(); err != nil {
+		return err
 	}
-	c.logFiles = append(c.logFiles, files[0])
-	c.errFiles = append(c.errFiles, files[1])
 
-	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("failed to complete the command: %v; see %q for logs, and %q for errors",
-			err, files[0], files[1])
+	ok, err := c.verifyRefAndCommit()
+	if err != nil || !ok {
+		return fmt.Errorf("failed to verify ref and commit: %v", err)
 	}
-	log.Printf("'git clone' completed. See %q, and %q for logs, and errors.", files[0], files[1])
 
 	return nil
 }
 
-func (c *GitClient) checkoutGitCommit() error {
+// runGitCommand runs the git command with the given arguments in the current
+// directory, and saves its outputs to temp files.
+func (c *GitClient) runGitCommand(args ...string) error {
 	//#nosec G204 -- Input from user config file.
-	cmd := exec.Command("git", "checkout", c.sourceDigest.Value)
+	cmd := exec.Command("git", args...)
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -455,7 +840,7 @@ func (c *GitClient) checkoutGitCommit() error {
 	}
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("couldn't start the 'git checkout' command: %v", err)
+		return fmt.Errorf("couldn't start the 'git %s' command: %v", strings.Join(args, " "), err)
 	}
 
 	files, err := saveToTempFile(c.verbose, stdout, stderr)
@@ -469,24 +854,24 @@ func (c *GitClient) checkoutGitCommit() error {
 		return fmt.Errorf("failed to complete the command: %v; see %q for logs, and %q for errors",
 			err, files[0], files[1])
 	}
-
-	ok, err := c.verifyRefAndCommit()
-	if err != nil || !ok {
-		return fmt.Errorf("failed to verify ref and commit: %v", err)
-	}
+	log.Printf("'git %s' completed. See %q, and %q for logs, and errors.", strings.Join(args, " "), files[0], files[1])
 
 	return nil
 }
 
 type tempFileResult struct {
-	File *os.File
-	Err  error
+	// Index of the reader in the arguments of saveToTempFile.
+	Index int
+	File  *os.File
+	Err   error
 }
 
 // A helper function used by saveToTempFile to process one individual file.
 // This should be called in a goroutine, and the channels passed in should be owned by the caller,
 // and remain open until the goroutine completes.
-func saveOneTempFile(verbose bool, reader io.Reader, fileChannel chan tempFileResult, printChannel chan string) {
+func saveOneTempFile(verbose bool, index int, reader io.Reader, fileChannel chan tempFileResult,
+	printChannel chan string,
+) {
 	var allBytes []byte
 	scanner := bufio.NewScanner(reader)
 	for scanner.Scan() {
@@ -501,21 +886,22 @@ func saveOneTempFile(verbose bool, reader io.Reader, fileChannel chan tempFileRe
 
 	tmpfile, err := os.CreateTemp("", "log-*.txt")
 	if err != nil {
-		fileChannel <- tempFileResult{Err: err}
+		fileChannel <- tempFileResult{Index: index, Err: err}
 		return
 	}
 	defer tmpfile.Close()
 
 	if _, err := tmpfile.Write(allBytes); err != nil {
-		fileChannel <- tempFileResult{Err: fmt.Errorf("couldn't write bytes to tempfile: %v", err)}
+		fileChannel <- tempFileResult{Index: index, Err: fmt.Errorf("couldn't write bytes to tempfile: %v", err)}
 	} else {
-		fileChannel <- tempFileResult{File: tmpfile}
+		fileChannel <- tempFileResult{Index: index, File: tmpfile}
 	}
 }
 
 // saveToTempFile creates a tempfile in `/tmp` and writes the content of the
 // given readers to that file.
-// It processes all provided readers concurrently.
+// It processes all provided readers concurrently, and returns the names of the
+// files in the order of the readers.
 func saveToTempFile(verbose bool, readers ...io.Reader) ([]string, error) {
 	if verbose {
 		fmt.Print("\n\n>>>>>>>>>>>>>> output from command <<<<<<<<<<<<<<\n")
@@ -527,12 +913,12 @@ func saveToTempFile(verbose bool, readers ...io.Reader) ([]string, error) {
 	printChannel := make(chan string)
 
 	// Start a goroutine to process each Reader concurrently.
-	for _, reader := range readers {
+	for i, reader := range readers {
 		wg.Add(1)
-		go func(reader io.Reader) {
+		go func(i int, reader io.Reader) {
 			defer wg.Done()
-			saveOneTempFile(verbose, reader, fileChannel, printChannel)
-		}(reader)
+			saveOneTempFile(verbose, i, reader, fileChannel, printChannel)
+		}(i, reader)
 	}
 
 	// Close the channel once all goroutines have finished.
@@ -553,12 +939,12 @@ func saveToTempFile(verbose bool, readers ...io.Reader) ([]string, error) {
 		fmt.Println(line)
 	}
 
-	var files []string
+	files := make([]string, len(readers))
 	for result := range fileChannel {
 		if result.Err != nil {
 			return nil, result.Err
 		}
-		files = append(files, result.File.Name())
+		files[result.Index] = result.File.Name()
 	}
 
 	return files, nil
@@ -578,72 +964,93 @@ func CheckExistingFiles(pattern string) error {
 	return fmt.Errorf("the specified pattern (%q) matches %d existing files; expected no matches", pattern, len(matches))
 }
 
-// Finds all files matching the given pattern, measures the SHA256 digest of
-// each file, and returns filenames and digests as an array of intoto.Subject.
+// checkExistingArtifacts checks that none of the artifact patterns of the
+// build config match existing files, excluding the ones matching the excludes.
+func checkExistingArtifacts(bc *BuildConfig) error {
+	for _, pattern := range bc.ArtifactPatterns() {
+		matches, err := findArtifacts([]string{pattern}, bc.ArtifactExcludes)
+		if err != nil {
+			return err
+		}
+		if len(matches) > 0 {
+			return fmt.Errorf("the specified pattern (%q) matches %d existing files; expected no matches", pattern, len(matches))
+		}
+	}
+	return nil
+}
+
+// Finds all files matching any of the given patterns and none of the
+// excludes, measures the SHA256 digest of each file, and returns filenames and
+// digests as an array of intoto.Subject.
+// The subjects are named using the path relative to the root of the source
+// repository if preservePaths is true, or the base name of the file otherwise.
+// It also returns the mapping from the subject names to the relative paths.
+// It fails if two files have the same subject name.
 // This also writes the output to a configured output folder, if provided.
-// Precondition: The pattern is a relative file path pattern.
-func inspectAndWriteArtifacts(pattern, outputFolder, root string) ([]intoto.Subject, error) {
-	matches, err := filepath.Glob(pattern)
-	// The only possible error is ErrBadPattern.
+// Precondition: The patterns are relative file path patterns.
+func inspectAndWriteArtifacts(patterns, excludes []string, outputFolder, root string, preservePaths bool,
+) ([]intoto.Subject, map[string]string, error) {
+	matches, err := findArtifacts(patterns, excludes)
 	if err != nil {
-		return nil, fmt.Errorf("the pattern (%q) is malformed: %v", pattern, err)
+		return nil, nil, err
 	}
 
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("no files matching the pattern %q: %v", pattern, err)
+		return nil, nil, fmt.Errorf("no files matching the patterns %q", patterns)
 	}
 
-	var subjects []intoto.Subject
-	for _, path := range matches {
-		data, err := utils.SafeReadFile(path)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't read file %q: %v", path, err)
+	names := make([]string, len(matches))
+	relPaths := make([]string, len(matches))
+	paths := map[string]string{}
+	for i, path := range matches {
+		// Compute the path relative to the root of the source repository.
+		absPath := path
+		if root != "" {
+			absPath, err = filepath.Abs(path)
+			if err != nil {
+				return nil, nil, err
+			}
 		}
-
-		// Write intoto subjects to output
-		subject, err := toIntotoSubject(data, path)
+		relPath, err := filepath.Rel(root, absPath)
 		if err != nil {
-			return nil, err
+			return nil, nil, fmt.Errorf("Root %s Path %s: %w", root, absPath, err)
 		}
-		subjects = append(subjects, *subject)
 
-		if outputFolder != "" {
-			// Write output file to output folder using the path relative to the root
-			// of the source repository.
-			if root != "" {
-				path, err = filepath.Abs(path)
-				if err != nil {
-					return nil, err
-				}
-			}
-			relPath, err := filepath.Rel(root, path)
-			if err != nil {
-				return nil, fmt.Errorf("Root %s Path %s: %w", root, path, err)
-			}
-			w, err := utils.CreateNewFileUnderDirectory(relPath, outputFolder, os.O_WRONLY)
-			if err != nil {
-				return nil, fmt.Errorf("creating new output file: %v", err)
-			}
-			if _, err := w.Write(data); err != nil {
-				return nil, fmt.Errorf("writing output file: %v", err)
-			}
+		name := filepath.Base(path)
+		if preservePaths {
+			name = filepath.ToSlash(relPath)
+		}
+		if other, ok := paths[name]; ok {
+			return nil, nil, fmt.Errorf("%w: %q and %q have the same subject name %q",
+				errSubjectCollision, other, filepath.ToSlash(relPath), name)
 		}
+		paths[name] = filepath.ToSlash(relPath)
+		names[i] = name
+		relPaths[i] = relPath
 	}
 
-	return subjects, nil
-}
+	opts := utils.HashOptions{Algorithms: []string{utils.SHA256}}
+	if outputFolder != "" {
+		// Write output files to the output folder using the paths relative to
+		// the root of the source repository, while hashing them.
+		opts.Output = func(i int) (io.Writer, error) {
+			return utils.CreateNewFileUnderDirectory(relPaths[i], outputFolder, os.O_WRONLY)
+		}
+	}
+	digests, err := utils.HashFiles(matches, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("couldn't hash the artifacts: %w", err)
+	}
 
-// Reads the file in the given path and returns its name and digest wrapped in
-// an intoto.Subject.
-func toIntotoSubject(data []byte, filePath string) (*intoto.Subject, error) {
-	sum256 := sha256.Sum256(data)
-	digest := hex.EncodeToString(sum256[:])
-	name := filepath.Base(filePath)
-	subject := &intoto.Subject{
-		Name:   name,
-		Digest: map[string]string{"sha256": digest},
+	subjects := make([]intoto.Subject, len(matches))
+	for i, name := range names {
+		subjects[i] = intoto.Subject{
+			Name:   name,
+			Digest: map[string]string{utils.SHA256: digests[i][utils.SHA256]},
+		}
 	}
-	return subject, nil
+
+	return subjects, paths, nil
 }
 
 // Cleanup removes the generated temp files. But it might not be able to remove
@@ -675,19 +1082,15 @@ func ParseProvenance(bytes []byte) (*ProvenanceStatementSLSA1, error) {
 	}
 
 	// ExternalParameters is an interface in slsa1.ProvenancePredicate, so we
-	// marshal and unmarshal it as an instance of ContainerBasedExternalParameters
-	// to be able to use the actual type.
-	var ep ContainerBasedExternalParameters
-	b, err := json.Marshal(statement.Predicate.BuildDefinition.ExternalParameters)
+	// decode it as an instance of ContainerBasedExternalParameters, using the
+	// schema of the build type, to be able to use the actual type.
+	ep, err := decodeExternalParameters(statement.Predicate.BuildDefinition.BuildType,
+		statement.Predicate.BuildDefinition.ExternalParameters)
 	if err != nil {
-		return nil, fmt.Errorf("could not marshal map into JSON bytes: %v", err)
-	}
-
-	if err = json.Unmarshal(b, &ep); err != nil {
-		return nil, fmt.Errorf("could not unmarshal JSON bytes into a BuildConfig: %v", err)
+		return nil, err
 	}
 
-	statement.Predicate.BuildDefinition.ExternalParameters = ep
+	statement.Predicate.BuildDefinition.ExternalParameters = *ep
 
 	return &statement, nil
 }
@@ -700,29 +1103,44 @@ func (p *ProvenanceStatementSLSA1) ToDockerBuildConfig(forceCheckout bool) (*Doc
 		return nil, fmt.Errorf("failed to cast ExternalParameters to ContainerBasedExternalParameters")
 	}
 
-	di, err := validateDockerImage(ep.BuilderImage.URI)
-	if err != nil {
-		return nil, fmt.Errorf("validating Docker image URI: %v", err)
-	}
-	if di.Digest.Value != ep.BuilderImage.Digest[di.Digest.Alg] {
-		return nil, fmt.Errorf("invalid Docker image digest")
+	// The builder image is not set if it is built from the builder Dockerfile.
+	var di DockerImage
+	if ep.BuilderImage.URI != "" {
+		image, err := validateDockerImage(ep.BuilderImage.URI)
+		if err != nil {
+			return nil, fmt.Errorf("validating Docker image URI: %v", err)
+		}
+		if image.Digest.Value != ep.BuilderImage.Digest[image.Digest.Alg] {
+			return nil, fmt.Errorf("invalid Docker image digest")
+		}
+		di = *image
 	}
 
-	val, ok := ep.Source.Digest["sha1"]
-	if !ok {
-		return nil, fmt.Errorf("missing sha1 digest for source")
-	}
-	sd := Digest{
-		Alg:   "sha1",
-		Value: val,
+	sd, err := sourceDigest(ep.Source.Digest)
+	if err != nil {
+		return nil, err
 	}
 
 	return &DockerBuildConfig{
 		SourceRepo:      ep.Source.URI,
-		SourceDigest:    sd,
-		BuilderImage:    *di,
+		SourceDigest:    *sd,
+		BuilderImage:    di,
 		BuildConfigPath: ep.ConfigPath,
 		ForceCheckout:   forceCheckout,
 		Verbose:         false,
 	}, nil
 }
+
+// sourceDigestAlgs are the algorithms of the digests of the sources supported
+// by the Fetchers, in order of preference.
+var sourceDigestAlgs = []string{"sha1", gitTreeAlg, utils.SHA256, utils.SHA512}
+
+// sourceDigest returns the digest of the sources that is used for fetching them.
+func sourceDigest(digests map[string]string) (*Digest, error) {
+	for _, alg := range sourceDigestAlgs {
+		if val, ok := digests[alg]; ok {
+			return &Digest{Alg: alg, Value: val}, nil
+		}
+	}
+	return nil, fmt.Errorf("missing a %q digest for source", sourceDigestAlgs)
+}
@@ -15,6 +15,7 @@
 package pkg
 
 import (
+	"context"
 	"errors"
 	"os"
 	"path/filepath"
@@ -24,6 +25,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
 )
 
 func Test_CreateBuildDefinition(t *testing.T) {
@@ -57,6 +59,44 @@ func Test_CreateBuildDefinition(t *testing.T) {
 	}
 }
 
+func Test_CreateBuildDefinition_steps(t *testing.T) {
+	const codegenImage = "codegen@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	config := &DockerBuildConfig{
+		SourceRepo:   "git+https://github.com/slsa-framework/slsa-github-generator@refs/heads/main",
+		SourceDigest: Digest{Alg: "sha1", Value: "cf5804b5c6f1a4b2a0b03401a487dfdfbe3a5f00"},
+		BuilderImage: DockerImage{
+			Name:   "bash",
+			Digest: Digest{Alg: "sha256", Value: "9e2ba52487d945504d250de186cb4fe2e3ba023ed2921dd6ac8b97ed43e76af9"},
+		},
+		BuildConfigPath: "config.toml",
+	}
+
+	db := &DockerBuild{
+		config: config,
+		buildConfig: &BuildConfig{
+			ArtifactPath: "out/app",
+			Steps: []BuildStep{
+				{Command: []string{"codegen"}, BuilderImage: codegenImage},
+				{Command: []string{"build"}},
+				{Command: []string{"codegen", "--check"}, BuilderImage: codegenImage},
+				{Command: []string{"build"}, BuilderImage: config.BuilderImage.ToString()},
+			},
+		},
+	}
+
+	got := db.CreateBuildDefinition().ResolvedDependencies
+	want := []slsa1.ResourceDescriptor{
+		sourceArtifact(config),
+		{
+			URI:    codegenImage,
+			Digest: map[string]string{"sha256": "1111111111111111111111111111111111111111111111111111111111111111"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected resolved dependencies (-want +got):\n%s", diff)
+	}
+}
+
 func Test_GitClient_verifyOrFetchRepo(t *testing.T) {
 	config := &DockerBuildConfig{
 		// Use a small repo for test
@@ -179,14 +219,14 @@ func Test_inspectArtifacts(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := inspectAndWriteArtifacts(pattern, out, filepath.Dir(wd))
+	got, _, err := inspectAndWriteArtifacts([]string{pattern}, nil, out, filepath.Dir(wd), false)
 	if err != nil {
 		t.Fatalf("failed to inspect artifacts: %v", err)
 	}
 
 	s1 := intoto.Subject{
 		Name:   "build-definition.json",
-		Digest: map[string]string{"sha256": "ab5582bfb6128c534583e1fea92421158c9de5e72e86c78cf550a8adcbf12db5"},
+		Digest: map[string]string{"sha256": "88f5ca47e64439376e37b4540287e76310edb6f17cde621127dc68d35e6d1034"},
 	}
 	s2 := intoto.Subject{
 		Name:   "config.toml",
@@ -229,14 +269,14 @@ func Test_inspectArtifactsNoRoot(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	got, err := inspectAndWriteArtifacts(pattern, out, "")
+	got, _, err := inspectAndWriteArtifacts([]string{pattern}, nil, out, "", false)
 	if err != nil {
 		t.Fatalf("failed to inspect artifacts: %v", err)
 	}
 
 	s1 := intoto.Subject{
 		Name:   "build-definition.json",
-		Digest: map[string]string{"sha256": "ab5582bfb6128c534583e1fea92421158c9de5e72e86c78cf550a8adcbf12db5"},
+		Digest: map[string]string{"sha256": "88f5ca47e64439376e37b4540287e76310edb6f17cde621127dc68d35e6d1034"},
 	}
 	s2 := intoto.Subject{
 		Name:   "config.toml",
@@ -298,7 +338,7 @@ func Test_Builder_SetUpBuildState(t *testing.T) {
 		config:      config,
 	}
 
-	db, err := b.SetUpBuildState()
+	db, err := b.SetUpBuildState(context.Background())
 	if err != nil {
 		t.Fatalf("couldn't set up build state: %v", err)
 	}
@@ -315,6 +355,9 @@ func Test_ParseProvenance(t *testing.T) {
 	if err != nil {
 		t.Fatalf("%v", err)
 	}
+	// The provenance uses the draft build type, whose external parameters are
+	// migrated to the ones of the current build type.
+	want.BuildType = ContainerBasedBuildTypeDraft
 
 	if diff := cmp.Diff(got, want); diff != "" {
 		t.Error(diff)
@@ -361,3 +404,126 @@ func loadProvenance(t *testing.T) ProvenanceStatementSLSA1 {
 	}
 	return *provenance
 }
+
+func Test_inspectAndWriteArtifacts_preservePaths(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	root := t.TempDir()
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"out/linux", "out/darwin"} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "app"), []byte(dir), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _, err = inspectAndWriteArtifacts([]string{"out/*/app"}, nil, "", "", false)
+	if !errors.Is(err, errSubjectCollision) {
+		t.Fatalf("unexpected error: %v", cmp.Diff(err, errSubjectCollision, cmpopts.EquateErrors()))
+	}
+
+	out := t.TempDir()
+	got, paths, err := inspectAndWriteArtifacts([]string{"out/*/app"}, nil, out, root, true)
+	if err != nil {
+		t.Fatalf("failed to inspect artifacts: %v", err)
+	}
+	want := []intoto.Subject{
+		{
+			Name:   "out/darwin/app",
+			Digest: map[string]string{"sha256": "36ceed16a7420ce42389724c127dc907f1a745d7e64c4f3c39b007c1b845d939"},
+		},
+		{
+			Name:   "out/linux/app",
+			Digest: map[string]string{"sha256": "9d0a1c130cf086019af4fc5882dee7b943a51dd68427cc6c6815dff253e3b45e"},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+	}
+	wantPaths := map[string]string{"out/darwin/app": "out/darwin/app", "out/linux/app": "out/linux/app"}
+	if diff := cmp.Diff(wantPaths, paths); diff != "" {
+		t.Errorf("unexpected paths (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(out, "out/linux/app")); err != nil {
+		t.Errorf("error checking output file: %v", err)
+	}
+}
+
+func Test_saveToTempFile(t *testing.T) {
+	t.Parallel()
+
+	// The files are returned in the order of the readers, regardless of the
+	// order in which they are written.
+	files, err := saveToTempFile(false, strings.NewReader(strings.Repeat("out\n", 10000)), strings.NewReader("err"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		defer os.Remove(f)
+		content, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, string(content))
+	}
+	if diff := cmp.Diff([]string{strings.Repeat("out\n", 10000), "err\n"}, got); diff != "" {
+		t.Errorf("unexpected contents (-want +got):\n%s", diff)
+	}
+}
+
+func Test_DockerBuild_LogByproducts(t *testing.T) {
+	rt := &testRuntime{run: []string{"sh", "-c", "echo out; echo err >&2"}}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		buildConfig: &BuildConfig{
+			Hermetic: true,
+			Prefetch: &BuildStep{Command: []string{"make", "deps"}},
+			Command:  []string{"make"},
+		},
+		runtime: rt,
+	}
+	if err := runDockerRun(context.Background(), db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, l := range db.logs {
+		defer os.Remove(l.path)
+	}
+
+	out := t.TempDir()
+	got, err := db.LogByproducts(out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	outDigest := map[string]string{"sha256": "54034ac5c6e9ea95734ec2b729fd6d62abf64af34a9f9ce5d466cb788191a73d"}
+	errDigest := map[string]string{"sha256": "2ccde4875ec595757efdf23d7b1336fcd69cf0fb869310b12a0d219c52817b20"}
+	want := []slsa1.ResourceDescriptor{
+		{Name: "prefetch.stdout.log", Digest: outDigest, MediaType: "text/plain"},
+		{Name: "prefetch.stderr.log", Digest: errDigest, MediaType: "text/plain"},
+		{Name: "step-1.stdout.log", Digest: outDigest, MediaType: "text/plain"},
+		{Name: "step-1.stderr.log", Digest: errDigest, MediaType: "text/plain"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected byproducts (-want +got):\n%s", diff)
+	}
+	content, err := os.ReadFile(filepath.Join(out, buildLogsDir, "step-1.stderr.log"))
+	if err != nil {
+		t.Fatalf("the log was not copied: %v", err)
+	}
+	if string(content) != "err\n" {
+		t.Errorf("unexpected log: %q", content)
+	}
+}
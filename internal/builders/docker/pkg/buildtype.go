@@ -0,0 +1,206 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the registry of the versions of the container-based build
+// type, and the migrations of their external parameters to the current schema.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// errBuildType indicates an unsupported build type, or external parameters
+// that do not match the schema of their build type.
+var errBuildType = errors.New("build type")
+
+// buildTypeVersion is a version of the container-based build type.
+type buildTypeVersion struct {
+	// next is the build type that the external parameters of this version are
+	// migrated to. It is empty for ContainerBasedBuildType, whose schema is
+	// ContainerBasedExternalParameters.
+	next string
+
+	// migrate converts the JSON-decoded external parameters of this version
+	// to the schema of the next version, in place.
+	migrate func(ep map[string]any) error
+}
+
+// buildTypes is the registry of the supported container-based build types,
+// keyed by their URI.
+var buildTypes = map[string]buildTypeVersion{
+	ContainerBasedBuildType: {},
+	ContainerBasedBuildTypeDraft: {
+		next:    ContainerBasedBuildType,
+		migrate: migrateDraftExternalParameters,
+	},
+}
+
+// draftBuildConfigKeys maps the keys of the build config in the external
+// parameters of ContainerBasedBuildTypeDraft, which are the names of the
+// fields of BuildConfig when that build type was released, to the keys of
+// ContainerBasedBuildType.
+var draftBuildConfigKeys = map[string]string{
+	"ArtifactPath": "artifactPath",
+	"Command":      "command",
+}
+
+// migrateDraftExternalParameters migrates the external parameters of
+// ContainerBasedBuildTypeDraft to ContainerBasedBuildType, by renaming the keys
+// of the build config.
+func migrateDraftExternalParameters(ep map[string]any) error {
+	config, ok := ep["buildConfig"].(map[string]any)
+	if !ok {
+		return fmt.Errorf("missing buildConfig")
+	}
+	if err := renameKeys(config, draftBuildConfigKeys); err != nil {
+		return fmt.Errorf("buildConfig: %w", err)
+	}
+	return nil
+}
+
+// renameKeys renames the keys of m according to names. It fails if a key is
+// present under both its old and new names.
+func renameKeys(m map[string]any, names map[string]string) error {
+	for old, name := range names {
+		v, ok := m[old]
+		if !ok || old == name {
+			continue
+		}
+		if _, ok := m[name]; ok {
+			return fmt.Errorf("both %q and %q are set", old, name)
+		}
+		delete(m, old)
+		m[name] = v
+	}
+	return nil
+}
+
+// decodeExternalParameters decodes the external parameters of a provenance
+// with the given build type, after migrating them to the schema of
+// ContainerBasedBuildType. Unknown parameters are rejected, since they could
+// not be replayed by a rebuild.
+func decodeExternalParameters(buildType string, ep any) (*ContainerBasedExternalParameters, error) {
+	b, err := json.Marshal(ep)
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal map into JSON bytes: %v", err)
+	}
+	var params map[string]any
+	if err := json.Unmarshal(b, &params); err != nil {
+		return nil, fmt.Errorf("%w: external parameters must be an object: %v", errBuildType, err)
+	}
+
+	for t := buildType; ; {
+		v, ok := buildTypes[t]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported build type %q", errBuildType, t)
+		}
+		if v.next == "" {
+			break
+		}
+		if err := v.migrate(params); err != nil {
+			return nil, fmt.Errorf("%w: migrating the external parameters of %q: %w", errBuildType, t, err)
+		}
+		t = v.next
+	}
+
+	// encoding/json matches the keys case-insensitively, which would accept
+	// the keys of the draft build type without migrating them.
+	if err := checkFieldNames(params, reflect.TypeOf(ContainerBasedExternalParameters{})); err != nil {
+		return nil, fmt.Errorf("%w: invalid external parameters for %q: %w", errBuildType, buildType, err)
+	}
+	if b, err = json.Marshal(params); err != nil {
+		return nil, fmt.Errorf("could not marshal map into JSON bytes: %v", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	var result ContainerBasedExternalParameters
+	if err := d.Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: invalid external parameters for %q: %v", errBuildType, buildType, err)
+	}
+	return &result, nil
+}
+
+// checkFieldNames checks that the keys of the JSON objects in v, a decoded
+// JSON value, are exactly the JSON names of the fields of the corresponding
+// structs in t. Values of another type than expected are left to the decoder.
+func checkFieldNames(v any, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for k, fv := range m {
+			ft, ok := fields[k]
+			if !ok {
+				return fmt.Errorf("unknown field %q", k)
+			}
+			if err := checkFieldNames(fv, ft); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		s, ok := v.([]any)
+		if !ok {
+			return nil
+		}
+		for i, e := range s {
+			if err := checkFieldNames(e, t.Elem()); err != nil {
+				return fmt.Errorf("%d: %w", i, err)
+			}
+		}
+	case reflect.Map:
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		for k, e := range m {
+			if err := checkFieldNames(e, t.Elem()); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// jsonFields returns the types of the exported fields of the struct type t,
+// keyed by their JSON names.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
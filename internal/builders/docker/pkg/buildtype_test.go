@@ -0,0 +1,169 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+func Test_ParseProvenance_buildTypes(t *testing.T) {
+	t.Parallel()
+
+	const source = `"source": {"uri": "git+https://github.com/slsa-framework/slsa-github-generator", "digest": {"sha1": "cf58"}}`
+	want := ContainerBasedExternalParameters{
+		Source: slsa1.ResourceDescriptor{
+			URI:    "git+https://github.com/slsa-framework/slsa-github-generator",
+			Digest: map[string]string{"sha1": "cf58"},
+		},
+		ConfigPath: "build.toml",
+		Config: BuildConfig{
+			ArtifactPaths: []string{"out/*"},
+			Hermetic:      true,
+			Prefetch:      &BuildStep{Command: []string{"go", "mod", "download"}},
+			Steps: []BuildStep{
+				{Command: []string{"protoc"}, BuilderImage: "protoc@sha256:1111"},
+				{Command: []string{"make"}, Env: map[string]string{"Command": "make"}},
+			},
+			BuilderDockerfile: "Dockerfile",
+			PidsLimit:         64,
+		},
+	}
+
+	tests := []struct {
+		name      string
+		buildType string
+		config    string
+		want      *ContainerBasedExternalParameters
+		err       error
+	}{
+		{
+			name:      "v1",
+			buildType: ContainerBasedBuildType,
+			config: `{"artifactPaths": ["out/*"], "hermetic": true, "prefetch": {"command": ["go", "mod", "download"]},
+				"steps": [{"command": ["protoc"], "builderImage": "protoc@sha256:1111"},
+					{"command": ["make"], "env": {"Command": "make"}}],
+				"builderDockerfile": "Dockerfile", "pidsLimit": 64}`,
+			want: &want,
+		},
+		{
+			name:      "draft",
+			buildType: ContainerBasedBuildTypeDraft,
+			config:    `{"ArtifactPath": "out/app", "Command": ["make"]}`,
+			want: &ContainerBasedExternalParameters{
+				Source:     want.Source,
+				ConfigPath: "build.toml",
+				Config:     BuildConfig{ArtifactPath: "out/app", Command: []string{"make"}},
+			},
+		},
+		{
+			name:      "draft with a field added after the draft",
+			buildType: ContainerBasedBuildTypeDraft,
+			config:    `{"ArtifactPath": "out/app", "Command": ["make"], "Hermetic": true}`,
+			err:       errBuildType,
+		},
+		{
+			name:      "unknown build type",
+			buildType: "https://slsa.dev/container-based-build/v0.2?draft",
+			config:    `{"artifactPaths": ["out/*"]}`,
+			err:       errBuildType,
+		},
+		{
+			name:      "unknown parameter",
+			buildType: ContainerBasedBuildType,
+			config:    `{"artifactPaths": ["out/*"], "privileged": true}`,
+			err:       errBuildType,
+		},
+		{
+			name:      "parameter with another case",
+			buildType: ContainerBasedBuildType,
+			config:    `{"artifactPaths": ["out/*"], "Hermetic": true}`,
+			err:       errBuildType,
+		},
+		{
+			name:      "draft with conflicting keys",
+			buildType: ContainerBasedBuildTypeDraft,
+			config:    `{"ArtifactPath": "out/app", "artifactPath": "dist/app"}`,
+			err:       errBuildType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			statement := fmt.Sprintf(`{"predicate": {"buildDefinition": {"buildType": %q, "externalParameters": {
+				%s, "configPath": "build.toml", "buildConfig": %s}}}}`, tt.buildType, source, tt.config)
+			got, err := ParseProvenance([]byte(statement))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.err, cmpopts.EquateErrors()))
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(*tt.want, got.Predicate.BuildDefinition.ExternalParameters); diff != "" {
+				t.Errorf("unexpected external parameters (-want +got):\n%s", diff)
+			}
+			// The recorded build type is kept.
+			if got.Predicate.BuildDefinition.BuildType != tt.buildType {
+				t.Errorf("unexpected build type: %q", got.Predicate.BuildDefinition.BuildType)
+			}
+		})
+	}
+}
+
+// Test_ParseProvenance_draftFixture parses a provenance generated before the
+// container-based build type was finalized.
+func Test_ParseProvenance_draftFixture(t *testing.T) {
+	t.Parallel()
+
+	b, err := os.ReadFile("../testdata/slsa1-provenance.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseProvenance(b)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := BuildConfig{
+		ArtifactPath: "config.toml",
+		Command:      []string{"cp", "internal/builders/docker/testdata/config.toml", "config.toml"},
+	}
+	ep := got.Predicate.BuildDefinition.ExternalParameters.(ContainerBasedExternalParameters)
+	if diff := cmp.Diff(want, ep.Config); diff != "" {
+		t.Errorf("unexpected build config (-want +got):\n%s", diff)
+	}
+
+	// Unknown keys are rejected, including in draft provenance.
+	var statement map[string]any
+	if err := json.Unmarshal(b, &statement); err != nil {
+		t.Fatal(err)
+	}
+	bd := statement["predicate"].(map[string]any)["buildDefinition"].(map[string]any)
+	config := bd["externalParameters"].(map[string]any)["buildConfig"].(map[string]any)
+	config["Env"] = map[string]any{"FOO": "bar"}
+	if b, err = json.Marshal(statement); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseProvenance(b); !errors.Is(err, errBuildType) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errBuildType, cmpopts.EquateErrors()))
+	}
+}
@@ -23,8 +23,11 @@ import (
 
 const (
 	// ContainerBasedBuildType is type for container-based builds.
-	// TODO(#1191): Update to the final BuildType URI.
-	ContainerBasedBuildType = "https://slsa.dev/container-based-build/v0.1?draft"
+	ContainerBasedBuildType = "https://github.com/slsa-framework/slsa-github-generator/container-based@v1"
+	// ContainerBasedBuildTypeDraft is the draft type for container-based
+	// builds, which was used before ContainerBasedBuildType. Provenance with
+	// this type can still be parsed and verified.
+	ContainerBasedBuildTypeDraft = "https://slsa.dev/container-based-build/v0.1?draft"
 	// SourceKey is the lookup key for source repository in ExternalParameters.
 	SourceKey = "source"
 	// BuilderImageKey is the lookup key for builder image in ExternalParameters.
@@ -35,6 +38,12 @@ const (
 	ArtifactPathKey = "artifactPath"
 	// CommandKey is the lookup key for the command in ExternalParameters.
 	CommandKey = "command"
+	// ContainerRuntimeKey is the lookup key for the container runtime in InternalParameters.
+	ContainerRuntimeKey = "containerRuntime"
+	// HermeticKey is the lookup key for whether the build is hermetic in InternalParameters.
+	HermeticKey = "hermetic"
+	// SubjectPathsKey is the lookup key for the paths of the subjects in InternalParameters.
+	SubjectPathsKey = "subjectPaths"
 )
 
 // ContainerBasedExternalParameters is a representation of the top level inputs to a
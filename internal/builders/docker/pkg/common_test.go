@@ -42,7 +42,7 @@ func Test_BuildDefinition(t *testing.T) {
 	}
 
 	want := &slsa1.ProvenanceBuildDefinition{
-		BuildType: "https://slsa.dev/container-based-build/v0.1?draft",
+		BuildType: "https://github.com/slsa-framework/slsa-github-generator/container-based@v1",
 		ExternalParameters: ContainerBasedExternalParameters{
 			Source:       wantSource,
 			BuilderImage: wantBuilderImage,
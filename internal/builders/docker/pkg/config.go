@@ -20,7 +20,13 @@ package pkg
 import (
 	"fmt"
 	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	toml "github.com/pelletier/go-toml"
 	"github.com/slsa-framework/slsa-github-generator/internal/utils"
@@ -30,14 +36,357 @@ import (
 type BuildConfig struct {
 	// The path, relative to the root of the git repository, where the artifact
 	// built by the `docker run` command is expected to be found.
-	ArtifactPath string `toml:"artifact_path"`
+	ArtifactPath string `toml:"artifact_path" json:"artifactPath,omitempty"`
+
+	// Patterns, relative to the root of the git repository, matching the
+	// artifacts built by the build. A `**` path segment matches zero or more
+	// directories. Only one of ArtifactPath and ArtifactPaths can be set.
+	ArtifactPaths []string `toml:"artifact_paths" json:"artifactPaths,omitempty"`
+
+	// Patterns matching the files to exclude from the artifacts, with the same
+	// syntax as ArtifactPaths.
+	ArtifactExcludes []string `toml:"artifact_excludes" json:"artifactExcludes,omitempty"`
 
-	// TODO(#1191): Add env and options if needed.
 	// Command to pass to `docker run`. The command is taken as an array
 	// instead of a single string to avoid unnecessary parsing. See
 	// https://docs.docker.com/engine/reference/builder/#cmd and
 	// https://man7.org/linux/man-pages/man3/exec.3.html for more details.
-	Command []string `toml:"command"`
+	Command []string `toml:"command" json:"command,omitempty"`
+
+	// Environment variables to set in the container, passed to `docker run`
+	// using `--env`.
+	Env map[string]string `toml:"env" json:"env,omitempty"`
+
+	// User to run the command as, passed to `docker run` using `--user`.
+	User string `toml:"user" json:"user,omitempty"`
+
+	// Entrypoint overriding the default entrypoint of the builder image,
+	// passed to `docker run` using `--entrypoint`.
+	Entrypoint string `toml:"entrypoint" json:"entrypoint,omitempty"`
+
+	// Absolute paths in the container where a tmpfs is mounted, passed to
+	// `docker run` using `--tmpfs`.
+	Tmpfs []string `toml:"tmpfs" json:"tmpfs,omitempty"`
+
+	// Whether to mount the root filesystem of the container as read-only,
+	// using `docker run --read-only`. The workspace is always writable.
+	ReadOnly bool `toml:"read_only" json:"readOnly,omitempty"`
+
+	// Ordered list of steps to run instead of Command. All steps share the
+	// workspace, so later steps can use the outputs of earlier ones. Exactly
+	// one of Command and Steps must be set.
+	Steps []BuildStep `toml:"steps" json:"steps,omitempty"`
+
+	// Whether to run the build steps in a hermetic container, without network
+	// access, with a read-only root filesystem and with all capabilities
+	// dropped. Dependencies can be fetched into the workspace in Prefetch.
+	Hermetic bool `toml:"hermetic" json:"hermetic,omitempty"`
+
+	// Step run with network access before the build steps of a hermetic
+	// build, to fetch the dependencies of the build into the workspace. It
+	// can only be set if Hermetic is set.
+	Prefetch *BuildStep `toml:"prefetch" json:"prefetch,omitempty"`
+
+	// Whether to name the subjects using the path of the artifacts relative
+	// to the root of the repository, instead of their base name.
+	PreservePaths bool `toml:"preserve_paths" json:"preservePaths,omitempty"`
+
+	// Path, relative to the root of the repository, of a Dockerfile from
+	// which the builder image is built before running the build steps. The
+	// root of the repository is the build context. It can only be set if no
+	// builder image is given as input.
+	BuilderDockerfile string `toml:"builder_dockerfile" json:"builderDockerfile,omitempty"`
+
+	// Names of the secrets required by the build, which are mounted in the
+	// containers of all the steps at /run/secrets/<name>. Only the names are
+	// recorded in the provenance, and the values must be supplied when
+	// running the build.
+	Secrets []string `toml:"secrets" json:"secrets,omitempty"`
+
+	// Maximum wall-clock duration of the build, including the prefetch step,
+	// as a Go duration, e.g., "1h30m". The build is not limited if empty.
+	Timeout string `toml:"timeout" json:"timeout,omitempty"`
+
+	// Number of CPUs available to each container, passed to `docker run`
+	// using `--cpus`, e.g., "1.5".
+	CPUs string `toml:"cpus" json:"cpus,omitempty"`
+
+	// Memory limit of each container, passed to `docker run` using
+	// `--memory`, e.g., "4g".
+	Memory string `toml:"memory" json:"memory,omitempty"`
+
+	// Maximum number of processes in each container, passed to `docker run`
+	// using `--pids-limit`.
+	PidsLimit int64 `toml:"pids_limit" json:"pidsLimit,omitempty"`
+}
+
+// BuildStep is a single `docker run` invocation in a multi-step build.
+type BuildStep struct {
+	// Command to pass to `docker run`.
+	Command []string `toml:"command" json:"command"`
+
+	// Builder image for this step in the form NAME@ALG:VALUE. If empty, the
+	// builder image of the build is used.
+	BuilderImage string `toml:"builder_image" json:"builderImage,omitempty"`
+
+	// Environment variables to set for this step, in addition to the ones of
+	// the build. Variables set here take precedence.
+	Env map[string]string `toml:"env" json:"env,omitempty"`
+}
+
+// envNameRegex matches valid environment variable names.
+var envNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// memoryRegex matches valid memory limits, in bytes or with a unit.
+var memoryRegex = regexp.MustCompile(`^[0-9]+[bkmgBKMG]?$`)
+
+// validate checks that the command, steps and docker run options in the
+// BuildConfig are valid.
+func (bc *BuildConfig) validate() error {
+	switch {
+	case len(bc.Command) > 0 && len(bc.Steps) > 0:
+		return fmt.Errorf("only one of command and steps can be set")
+	case len(bc.Command) == 0 && len(bc.Steps) == 0:
+		return fmt.Errorf("one of command and steps must be set")
+	}
+	for i, step := range bc.Steps {
+		if len(step.Command) == 0 {
+			return fmt.Errorf("step %d: command must be set", i)
+		}
+		if step.BuilderImage != "" {
+			if _, err := validateDockerImage(step.BuilderImage); err != nil {
+				return fmt.Errorf("step %d: builder image must be pinned by digest: %v", i, err)
+			}
+		}
+		if err := validateEnv(step.Env); err != nil {
+			return fmt.Errorf("step %d: %v", i, err)
+		}
+	}
+
+	if bc.Prefetch != nil {
+		if !bc.Hermetic {
+			return fmt.Errorf("prefetch can only be set for hermetic builds")
+		}
+		if len(bc.Prefetch.Command) == 0 {
+			return fmt.Errorf("prefetch: command must be set")
+		}
+		if bc.Prefetch.BuilderImage != "" {
+			if _, err := validateDockerImage(bc.Prefetch.BuilderImage); err != nil {
+				return fmt.Errorf("prefetch: builder image must be pinned by digest: %v", err)
+			}
+		}
+		if err := validateEnv(bc.Prefetch.Env); err != nil {
+			return fmt.Errorf("prefetch: %v", err)
+		}
+	}
+
+	if bc.ArtifactPath != "" && len(bc.ArtifactPaths) > 0 {
+		return fmt.Errorf("only one of artifact_path and artifact_paths can be set")
+	}
+	for _, pattern := range append(bc.ArtifactPatterns(), bc.ArtifactExcludes...) {
+		if err := validatePattern(pattern); err != nil {
+			return err
+		}
+	}
+
+	if err := validateEnv(bc.Env); err != nil {
+		return err
+	}
+	if strings.ContainsAny(bc.User, " \t\n=") {
+		return fmt.Errorf("invalid user %q", bc.User)
+	}
+	if strings.ContainsAny(bc.Entrypoint, "\n") {
+		return fmt.Errorf("invalid entrypoint %q", bc.Entrypoint)
+	}
+	if _, err := bc.resourceLimits(); err != nil {
+		return err
+	}
+	if bc.BuilderDockerfile != "" && !filepath.IsLocal(bc.BuilderDockerfile) {
+		return fmt.Errorf("builder_dockerfile %q must be a path relative to the root of the repository",
+			bc.BuilderDockerfile)
+	}
+	if err := validateSecretNames(bc.Secrets); err != nil {
+		return err
+	}
+	for _, p := range bc.Tmpfs {
+		mountPath, _, _ := strings.Cut(p, ":")
+		if !path.IsAbs(mountPath) {
+			return fmt.Errorf("tmpfs mount path %q must be absolute", mountPath)
+		}
+		if cleaned := path.Clean(mountPath); cleaned == "/" || cleaned == workspaceDir ||
+			strings.HasPrefix(cleaned, workspaceDir+"/") {
+			return fmt.Errorf("tmpfs cannot be mounted at %q", mountPath)
+		}
+	}
+	return nil
+}
+
+func validateEnv(env map[string]string) error {
+	for name := range env {
+		if !envNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid environment variable name %q", name)
+		}
+	}
+	return nil
+}
+
+// ResourceLimits are the limits of the resources used by a build.
+type ResourceLimits struct {
+	// Maximum wall-clock duration of the build. Zero means no limit.
+	Timeout time.Duration
+
+	// Number of CPUs available to each container.
+	CPUs string
+
+	// Memory limit of each container.
+	Memory string
+
+	// Maximum number of processes in each container. Zero means no limit.
+	PidsLimit int64
+}
+
+// validate checks that the limits are valid.
+func (l *ResourceLimits) validate() error {
+	if l.Timeout < 0 {
+		return fmt.Errorf("invalid timeout %q: must be positive", l.Timeout)
+	}
+	if l.CPUs != "" {
+		if cpus, err := strconv.ParseFloat(l.CPUs, 64); err != nil || cpus <= 0 {
+			return fmt.Errorf("invalid cpus %q: must be a positive number", l.CPUs)
+		}
+	}
+	if l.Memory != "" && !memoryRegex.MatchString(l.Memory) {
+		return fmt.Errorf("invalid memory %q: must be a number of bytes, optionally with a unit b, k, m or g", l.Memory)
+	}
+	if l.PidsLimit < 0 {
+		return fmt.Errorf("invalid pids limit %d: must be positive", l.PidsLimit)
+	}
+	return nil
+}
+
+// overrideResourceLimits sets the limits that are set in overrides, e.g., by
+// command-line flags, in the build config, so that the build config recorded
+// in the provenance describes the limits that the build runs with.
+func (bc *BuildConfig) overrideResourceLimits(overrides ResourceLimits) error {
+	if overrides.Timeout != 0 {
+		bc.Timeout = overrides.Timeout.String()
+	}
+	if overrides.CPUs != "" {
+		bc.CPUs = overrides.CPUs
+	}
+	if overrides.Memory != "" {
+		bc.Memory = overrides.Memory
+	}
+	if overrides.PidsLimit != 0 {
+		bc.PidsLimit = overrides.PidsLimit
+	}
+	_, err := bc.resourceLimits()
+	return err
+}
+
+// dockerRunFlags returns the `docker run` flags that limit the resources of
+// a container.
+func (l *ResourceLimits) dockerRunFlags() []string {
+	var flags []string
+	if l.CPUs != "" {
+		flags = append(flags, "--cpus="+l.CPUs)
+	}
+	if l.Memory != "" {
+		flags = append(flags, "--memory="+l.Memory)
+	}
+	if l.PidsLimit != 0 {
+		flags = append(flags, fmt.Sprintf("--pids-limit=%d", l.PidsLimit))
+	}
+	return flags
+}
+
+// resourceLimits returns the validated resource limits of the BuildConfig.
+func (bc *BuildConfig) resourceLimits() (ResourceLimits, error) {
+	l := ResourceLimits{CPUs: bc.CPUs, Memory: bc.Memory, PidsLimit: bc.PidsLimit}
+	if bc.Timeout != "" {
+		timeout, err := time.ParseDuration(bc.Timeout)
+		if err != nil {
+			return l, fmt.Errorf("invalid timeout %q: %v", bc.Timeout, err)
+		}
+		l.Timeout = timeout
+	}
+	return l, l.validate()
+}
+
+// ArtifactPatterns returns the patterns matching the artifacts of the build.
+func (bc *BuildConfig) ArtifactPatterns() []string {
+	if len(bc.ArtifactPaths) > 0 {
+		return bc.ArtifactPaths
+	}
+	if bc.ArtifactPath != "" {
+		return []string{bc.ArtifactPath}
+	}
+	return nil
+}
+
+// BuildSteps returns the steps of the build. A build with a single Command is
+// returned as a single step.
+func (bc *BuildConfig) BuildSteps() []BuildStep {
+	if len(bc.Steps) > 0 {
+		return bc.Steps
+	}
+	return []BuildStep{{Command: bc.Command}}
+}
+
+// hermeticDockerRunFlags are the `docker run` flags that isolate the build
+// steps of hermetic builds.
+var hermeticDockerRunFlags = []string{
+	"--network=none",
+	"--read-only",
+	"--cap-drop=ALL",
+	"--security-opt=no-new-privileges",
+}
+
+// dockerRunOptions returns the `docker run` flags for the options in the
+// BuildConfig and the given step. If isolated is true, the flags for running
+// the step hermetically are added. Environment variables are sorted by name so
+// that the flags are deterministic.
+func (bc *BuildConfig) dockerRunOptions(step *BuildStep, isolated bool) []string {
+	env := map[string]string{}
+	for name, v := range bc.Env {
+		env[name] = v
+	}
+	for name, v := range step.Env {
+		env[name] = v
+	}
+
+	var flags []string
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		flags = append(flags, fmt.Sprintf("--env=%s=%s", name, env[name]))
+	}
+	if bc.User != "" {
+		flags = append(flags, "--user="+bc.User)
+	}
+	if bc.Entrypoint != "" {
+		flags = append(flags, "--entrypoint="+bc.Entrypoint)
+	}
+	for _, p := range bc.Tmpfs {
+		flags = append(flags, "--tmpfs="+p)
+	}
+	if isolated {
+		flags = append(flags, hermeticDockerRunFlags...)
+	} else if bc.ReadOnly {
+		flags = append(flags, "--read-only")
+	}
+	return flags
+}
+
+// setsEnv returns whether the build config or the step sets the environment
+// variable.
+func (bc *BuildConfig) setsEnv(step *BuildStep, name string) bool {
+	_, inConfig := bc.Env[name]
+	_, inStep := step.Env[name]
+	return inConfig || inStep
 }
 
 // Digest specifies a digest values, including the name of the hash function
@@ -54,6 +403,11 @@ type DockerImage struct {
 	Digest Digest
 }
 
+// isSet returns true if the builder image is set.
+func (bi *DockerImage) isSet() bool {
+	return bi.Name != ""
+}
+
 // ToString returns the builder image in the form of NAME@ALG:VALUE.
 func (bi *DockerImage) ToString() string {
 	return fmt.Sprintf("%s@%s:%s", bi.Name, bi.Digest.Alg, bi.Digest.Value)
@@ -65,8 +419,19 @@ type DockerBuildConfig struct {
 	SourceDigest    Digest
 	BuilderImage    DockerImage
 	BuildConfigPath string
-	ForceCheckout   bool
-	Verbose         bool
+	// ContainerRuntime is the name of the container runtime. Docker is used
+	// if it is empty.
+	ContainerRuntime string
+	// BuilderImageLayout is the path to an OCI image layout directory or
+	// tarball, which the builder images are loaded from if it contains them.
+	BuilderImageLayout string
+	// Limits override the resource limits of the build config, and are
+	// recorded in it.
+	Limits ResourceLimits
+	// Secrets are the values of the secrets required by the build config.
+	Secrets       []Secret
+	ForceCheckout bool
+	Verbose       bool
 }
 
 // NewDockerBuildConfig validates the inputs and generates an instance of
@@ -81,22 +446,60 @@ func NewDockerBuildConfig(io *InputOptions) (*DockerBuildConfig, error) {
 		return nil, err
 	}
 
-	dockerImage, err := validateDockerImage(io.BuilderImage)
-	if err != nil {
-		return nil, err
+	// The builder image can be omitted if the build config builds it from a
+	// Dockerfile, which is checked once the config is loaded.
+	var dockerImage DockerImage
+	if io.BuilderImage != "" {
+		di, err := validateDockerImage(io.BuilderImage)
+		if err != nil {
+			return nil, err
+		}
+		dockerImage = *di
 	}
 
 	if err = utils.PathIsUnderCurrentDirectory(io.BuildConfigPath); err != nil {
 		return nil, fmt.Errorf("invalid build config path: %v", err)
 	}
 
+	if _, err := NewContainerRuntime(io.ContainerRuntime); err != nil {
+		return nil, err
+	}
+
+	limits := ResourceLimits{
+		Timeout:   io.Timeout,
+		CPUs:      io.CPUs,
+		Memory:    io.Memory,
+		PidsLimit: io.PidsLimit,
+	}
+	if err := limits.validate(); err != nil {
+		return nil, err
+	}
+
+	secrets, err := ParseSecrets(io.Secrets)
+	if err != nil {
+		return nil, err
+	}
+
+	// The layout is opened after the source repository is checked out, in
+	// another working directory.
+	layout := io.BuilderImageLayout
+	if layout != "" {
+		if layout, err = filepath.Abs(layout); err != nil {
+			return nil, fmt.Errorf("invalid builder image layout path: %v", err)
+		}
+	}
+
 	return &DockerBuildConfig{
-		SourceRepo:      io.SourceRepo,
-		SourceDigest:    *sourceRepoDigest,
-		BuilderImage:    *dockerImage,
-		BuildConfigPath: io.BuildConfigPath,
-		ForceCheckout:   io.ForceCheckout,
-		Verbose:         io.Verbose,
+		SourceRepo:         io.SourceRepo,
+		SourceDigest:       *sourceRepoDigest,
+		BuilderImage:       dockerImage,
+		BuildConfigPath:    io.BuildConfigPath,
+		ContainerRuntime:   io.ContainerRuntime,
+		BuilderImageLayout: layout,
+		Limits:             limits,
+		Secrets:            secrets,
+		ForceCheckout:      io.ForceCheckout,
+		Verbose:            io.Verbose,
 	}, nil
 }
 
@@ -174,5 +577,9 @@ func loadBuildConfigFromFile(path string) (*BuildConfig, error) {
 		return nil, fmt.Errorf("couldn't unmarshal toml file: %v", err)
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid build config: %v", err)
+	}
+
 	return &config, nil
 }
@@ -18,6 +18,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 )
@@ -88,3 +89,508 @@ func Test_NewDockerBuildConfig(t *testing.T) {
 		t.Error(diff)
 	}
 }
+
+// loadBuildConfigFromString writes the TOML content to a file in a temporary
+// working directory and loads it.
+func loadBuildConfigFromString(t *testing.T, content string) (*BuildConfig, error) {
+	t.Helper()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile("config.toml", []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return loadBuildConfigFromFile("config.toml")
+}
+
+func Test_LoadBuildConfigFromFile_dockerRunOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    string
+		want      *BuildConfig
+		wantFlags []string
+		wantErr   bool
+	}{
+		{
+			name: "all options",
+			config: `
+command = ["cargo", "build"]
+artifact_path = "target/release/app"
+user = "1000:1000"
+entrypoint = "/bin/sh"
+tmpfs = ["/tmp:size=64m", "/run"]
+read_only = true
+
+[env]
+RUSTFLAGS = "-C target-cpu=native"
+CC = "clang"
+`,
+			want: &BuildConfig{
+				Command:      []string{"cargo", "build"},
+				ArtifactPath: "target/release/app",
+				Env:          map[string]string{"RUSTFLAGS": "-C target-cpu=native", "CC": "clang"},
+				User:         "1000:1000",
+				Entrypoint:   "/bin/sh",
+				Tmpfs:        []string{"/tmp:size=64m", "/run"},
+				ReadOnly:     true,
+			},
+			wantFlags: []string{
+				"--env=CC=clang",
+				"--env=RUSTFLAGS=-C target-cpu=native",
+				"--user=1000:1000",
+				"--entrypoint=/bin/sh",
+				"--tmpfs=/tmp:size=64m",
+				"--tmpfs=/run",
+				"--read-only",
+			},
+		},
+		{
+			name: "no options",
+			config: `
+command = ["make"]
+artifact_path = "out/*"
+`,
+			want: &BuildConfig{
+				Command:      []string{"make"},
+				ArtifactPath: "out/*",
+			},
+		},
+		{
+			name: "invalid env name",
+			config: `
+command = ["make"]
+[env]
+"NOT-VALID" = "1"
+`,
+			wantErr: true,
+		},
+		{
+			name: "invalid user",
+			config: `
+command = ["make"]
+user = "root --privileged"
+`,
+			wantErr: true,
+		},
+		{
+			name: "relative tmpfs",
+			config: `
+command = ["make"]
+tmpfs = ["tmp"]
+`,
+			wantErr: true,
+		},
+		{
+			name: "tmpfs over workspace",
+			config: `
+command = ["make"]
+tmpfs = ["/workspace/out"]
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBuildConfigFromString(t, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantFlags, got.dockerRunOptions(&BuildStep{}, false)); diff != "" {
+				t.Errorf("unexpected flags (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_LoadBuildConfigFromFile_steps(t *testing.T) {
+	const codegenImage = "codegen@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+
+	tests := []struct {
+		name    string
+		config  string
+		want    *BuildConfig
+		wantErr bool
+	}{
+		{
+			name: "steps",
+			config: `
+artifact_path = "out/app"
+
+[env]
+CC = "clang"
+
+[[steps]]
+command = ["protoc", "--go_out=gen", "api.proto"]
+builder_image = "` + codegenImage + `"
+
+[[steps]]
+command = ["make", "app"]
+env = { CC = "gcc", CGO_ENABLED = "0" }
+`,
+			want: &BuildConfig{
+				ArtifactPath: "out/app",
+				Env:          map[string]string{"CC": "clang"},
+				Steps: []BuildStep{
+					{
+						Command:      []string{"protoc", "--go_out=gen", "api.proto"},
+						BuilderImage: codegenImage,
+					},
+					{
+						Command: []string{"make", "app"},
+						Env:     map[string]string{"CC": "gcc", "CGO_ENABLED": "0"},
+					},
+				},
+			},
+		},
+		{
+			name: "command and steps",
+			config: `
+command = ["make"]
+[[steps]]
+command = ["make"]
+`,
+			wantErr: true,
+		},
+		{
+			name:    "no command",
+			config:  `artifact_path = "out/app"`,
+			wantErr: true,
+		},
+		{
+			name: "empty step command",
+			config: `
+[[steps]]
+builder_image = "` + codegenImage + `"
+`,
+			wantErr: true,
+		},
+		{
+			name: "step image not pinned",
+			config: `
+[[steps]]
+command = ["make"]
+builder_image = "codegen:latest"
+`,
+			wantErr: true,
+		},
+		{
+			name: "step invalid env",
+			config: `
+[[steps]]
+command = ["make"]
+env = { "A-B" = "1" }
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBuildConfigFromString(t, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_BuildConfig_dockerRunOptions_step(t *testing.T) {
+	bc := &BuildConfig{
+		Env: map[string]string{"CC": "clang", "DEBUG": "1"},
+		Steps: []BuildStep{
+			{Command: []string{"make"}, Env: map[string]string{"CC": "gcc"}},
+		},
+	}
+	want := []string{"--env=CC=gcc", "--env=DEBUG=1"}
+	if diff := cmp.Diff(want, bc.dockerRunOptions(&bc.BuildSteps()[0], false)); diff != "" {
+		t.Errorf("unexpected flags (-want +got):\n%s", diff)
+	}
+}
+
+func Test_LoadBuildConfigFromFile_hermetic(t *testing.T) {
+	const fetchImage = "fetch@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+
+	tests := []struct {
+		name    string
+		config  string
+		want    *BuildConfig
+		wantErr bool
+	}{
+		{
+			name: "hermetic with prefetch",
+			config: `
+command = ["cargo", "build", "--offline"]
+hermetic = true
+
+[prefetch]
+command = ["cargo", "fetch"]
+builder_image = "` + fetchImage + `"
+`,
+			want: &BuildConfig{
+				Command:  []string{"cargo", "build", "--offline"},
+				Hermetic: true,
+				Prefetch: &BuildStep{
+					Command:      []string{"cargo", "fetch"},
+					BuilderImage: fetchImage,
+				},
+			},
+		},
+		{
+			name: "prefetch without hermetic",
+			config: `
+command = ["make"]
+[prefetch]
+command = ["make", "deps"]
+`,
+			wantErr: true,
+		},
+		{
+			name: "empty prefetch command",
+			config: `
+command = ["make"]
+hermetic = true
+[prefetch]
+env = { A = "1" }
+`,
+			wantErr: true,
+		},
+		{
+			name: "prefetch image not pinned",
+			config: `
+command = ["make"]
+hermetic = true
+[prefetch]
+command = ["make", "deps"]
+builder_image = "fetch:latest"
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBuildConfigFromString(t, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_BuildConfig_dockerRunOptions_isolated(t *testing.T) {
+	bc := &BuildConfig{
+		Command:  []string{"make"},
+		ReadOnly: true,
+		Hermetic: true,
+	}
+	want := []string{
+		"--network=none",
+		"--read-only",
+		"--cap-drop=ALL",
+		"--security-opt=no-new-privileges",
+	}
+	if diff := cmp.Diff(want, bc.dockerRunOptions(&bc.BuildSteps()[0], true)); diff != "" {
+		t.Errorf("unexpected flags (-want +got):\n%s", diff)
+	}
+}
+
+func Test_LoadBuildConfigFromFile_artifactPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    *BuildConfig
+		wantErr bool
+	}{
+		{
+			name: "artifact paths",
+			config: `
+command = ["cargo", "build", "--release"]
+artifact_paths = ["target/*/release/app", "dist/**/*.tar.gz"]
+artifact_excludes = ["**/*.d"]
+`,
+			want: &BuildConfig{
+				Command:          []string{"cargo", "build", "--release"},
+				ArtifactPaths:    []string{"target/*/release/app", "dist/**/*.tar.gz"},
+				ArtifactExcludes: []string{"**/*.d"},
+			},
+		},
+		{
+			name: "artifact path and paths",
+			config: `
+command = ["make"]
+artifact_path = "out/app"
+artifact_paths = ["out/**"]
+`,
+			wantErr: true,
+		},
+		{
+			name: "malformed pattern",
+			config: `
+command = ["make"]
+artifact_paths = ["out/["]
+`,
+			wantErr: true,
+		},
+		{
+			name: "malformed exclude",
+			config: `
+command = ["make"]
+artifact_path = "out/*"
+artifact_excludes = ["**/["]
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBuildConfigFromString(t, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_LoadBuildConfigFromFile_resourceLimits(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		want       *BuildConfig
+		wantLimits ResourceLimits
+		wantErr    bool
+	}{
+		{
+			name: "all limits",
+			config: `
+command = ["make"]
+timeout = "1h30m"
+cpus = "1.5"
+memory = "4g"
+pids_limit = 512
+`,
+			want: &BuildConfig{
+				Command:   []string{"make"},
+				Timeout:   "1h30m",
+				CPUs:      "1.5",
+				Memory:    "4g",
+				PidsLimit: 512,
+			},
+			wantLimits: ResourceLimits{Timeout: 90 * time.Minute, CPUs: "1.5", Memory: "4g", PidsLimit: 512},
+		},
+		{
+			name: "malformed timeout",
+			config: `
+command = ["make"]
+timeout = "1 hour"
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative timeout",
+			config: `
+command = ["make"]
+timeout = "-1m"
+`,
+			wantErr: true,
+		},
+		{
+			name: "zero cpus",
+			config: `
+command = ["make"]
+cpus = "0"
+`,
+			wantErr: true,
+		},
+		{
+			name: "malformed memory",
+			config: `
+command = ["make"]
+memory = "4 GB"
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative pids limit",
+			config: `
+command = ["make"]
+pids_limit = -1
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBuildConfigFromString(t, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected config (-want +got):\n%s", diff)
+			}
+			limits, err := got.resourceLimits()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.wantLimits, limits); diff != "" {
+				t.Errorf("unexpected limits (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_BuildConfig_overrideResourceLimits(t *testing.T) {
+	t.Parallel()
+
+	bc := BuildConfig{Timeout: "1h", CPUs: "2", Memory: "4g", PidsLimit: 512}
+	if err := bc.overrideResourceLimits(ResourceLimits{Timeout: time.Minute, Memory: "1g"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The overrides are recorded in the build config.
+	want := BuildConfig{Timeout: "1m0s", CPUs: "2", Memory: "1g", PidsLimit: 512}
+	if diff := cmp.Diff(want, bc); diff != "" {
+		t.Errorf("unexpected build config (-want +got):\n%s", diff)
+	}
+
+	got, err := bc.resourceLimits()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wantFlags := []string{"--cpus=2", "--memory=1g", "--pids-limit=512"}
+	if diff := cmp.Diff(wantFlags, got.dockerRunFlags()); diff != "" {
+		t.Errorf("unexpected flags (-want +got):\n%s", diff)
+	}
+	if got.Timeout != time.Minute {
+		t.Errorf("unexpected timeout: %v", got.Timeout)
+	}
+
+	if err := bc.overrideResourceLimits(ResourceLimits{Memory: "lots"}); err == nil {
+		t.Error("expected an error for an invalid memory limit")
+	}
+}
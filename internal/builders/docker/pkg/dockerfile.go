@@ -0,0 +1,243 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the support for building the builder image from a
+// Dockerfile in the source repository, before running the build steps.
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+// errBuilderDockerfile indicates an invalid builder Dockerfile, or a failure
+// to build the builder image from it.
+var errBuilderDockerfile = errors.New("builder dockerfile")
+
+// ociImageConfigMediaType is the media type of the config of an OCI image,
+// whose digest is the ID of the image built from a Dockerfile.
+const ociImageConfigMediaType = "application/vnd.oci.image.config.v1+json"
+
+// checkBuilderImage checks that exactly one of the builder image of the
+// config and the builder Dockerfile of the build config is set.
+func checkBuilderImage(config *DockerBuildConfig, bc *BuildConfig) error {
+	switch {
+	case config.BuilderImage.isSet() && bc.BuilderDockerfile != "":
+		return fmt.Errorf("%w: the builder image cannot be set when the build config sets builder_dockerfile",
+			errBuilderDockerfile)
+	case !config.BuilderImage.isSet() && bc.BuilderDockerfile == "":
+		return fmt.Errorf("the builder image must be set, unless the build config sets builder_dockerfile")
+	}
+	return nil
+}
+
+// dockerfileBaseImages returns the external images that the Dockerfile at the
+// path depends on: the base images of its stages, and the images used by
+// `COPY --from` and `RUN --mount=...,from=`, excluding `scratch` and the stages
+// of the Dockerfile. The images must be pinned by digest, so that they can be
+// recorded as resolved dependencies. Variables are not supported in image
+// references, since their values may not be pinned.
+func dockerfileBaseImages(p string) ([]DockerImage, error) {
+	instructions, err := dockerfileInstructions(p)
+	if err != nil {
+		return nil, err
+	}
+
+	var stages []string
+	var images []DockerImage
+	seen := map[string]bool{}
+	// add adds the external image referenced by an instruction.
+	add := func(ref, instruction string) error {
+		if ref == "scratch" || slices.Contains(stages, strings.ToLower(ref)) {
+			return nil
+		}
+		if strings.Contains(ref, "$") {
+			return fmt.Errorf("%w: variables are not supported in the image %q of %q, "+
+				"it must be written as NAME@sha256:DIGEST", errBuilderDockerfile, ref, instruction)
+		}
+		di, err := validateDockerImage(ref)
+		if err != nil {
+			return fmt.Errorf("%w: image %q of %q must be pinned by digest: %v", errBuilderDockerfile, ref, instruction, err)
+		}
+		if !seen[di.ToString()] {
+			seen[di.ToString()] = true
+			images = append(images, *di)
+		}
+		return nil
+	}
+	// addFrom adds the image referenced by a `from` flag, which can also be
+	// the name or the index of an earlier stage.
+	addFrom := func(from, instruction string) error {
+		if i, err := strconv.Atoi(from); err == nil {
+			if i < 0 || i >= len(stages) {
+				return fmt.Errorf("%w: no stage %d in %q", errBuilderDockerfile, i, instruction)
+			}
+			return nil
+		}
+		return add(from, instruction)
+	}
+
+	for _, instruction := range instructions {
+		fields := strings.Fields(instruction)
+		flags, args := dockerfileFlags(fields[1:])
+		switch strings.ToUpper(fields[0]) {
+		case "FROM":
+			if len(args) == 0 {
+				return nil, fmt.Errorf("%w: malformed instruction %q", errBuilderDockerfile, instruction)
+			}
+			if err := add(args[0], instruction); err != nil {
+				return nil, err
+			}
+			// The stage is added after its base image, which cannot be
+			// the stage itself.
+			name := ""
+			if len(args) == 3 && strings.EqualFold(args[1], "AS") {
+				name = strings.ToLower(args[2])
+			}
+			stages = append(stages, name)
+		case "COPY":
+			for _, from := range flags["from"] {
+				if err := addFrom(from, instruction); err != nil {
+					return nil, err
+				}
+			}
+		case "RUN":
+			for _, mount := range flags["mount"] {
+				for _, opt := range strings.Split(mount, ",") {
+					if from, ok := strings.CutPrefix(opt, "from="); ok {
+						if err := addFrom(from, instruction); err != nil {
+							return nil, err
+						}
+					}
+				}
+			}
+		}
+	}
+	return images, nil
+}
+
+// dockerfileFlags splits the arguments of an instruction into its leading
+// `--name=value` flags, keyed by name, and the remaining arguments.
+func dockerfileFlags(args []string) (map[string][]string, []string) {
+	flags := map[string][]string{}
+	for len(args) > 0 && strings.HasPrefix(args[0], "--") {
+		name, value, _ := strings.Cut(strings.TrimPrefix(args[0], "--"), "=")
+		flags[strings.ToLower(name)] = append(flags[strings.ToLower(name)], value)
+		args = args[1:]
+	}
+	return flags, args
+}
+
+// dockerfileInstructions returns the instructions of the Dockerfile at the
+// path, with their line continuations joined, and without the comments. The
+// escape character can be changed with the `escape` parser directive.
+func dockerfileInstructions(p string) ([]string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errBuilderDockerfile, err)
+	}
+	defer f.Close()
+
+	escape := `\`
+	directives := true
+	var instructions []string
+	var current strings.Builder
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// The parser directives are the comments of the form `# name=value`
+		// at the top of the file.
+		if directives {
+			d, ok := strings.CutPrefix(line, "#")
+			name, value, isDirective := strings.Cut(d, "=")
+			if ok && isDirective && !strings.ContainsAny(strings.TrimSpace(name), " \t") {
+				if strings.EqualFold(strings.TrimSpace(name), "escape") {
+					escape = strings.TrimSpace(value)
+				}
+				continue
+			}
+			directives = false
+		}
+		// Comments are removed, including within continued instructions.
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if l, ok := strings.CutSuffix(line, escape); ok {
+			current.WriteString(l + " ")
+			continue
+		}
+		current.WriteString(line)
+		instructions = append(instructions, current.String())
+		current.Reset()
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: reading %q: %w", errBuilderDockerfile, p, err)
+	}
+	if current.Len() > 0 {
+		instructions = append(instructions, current.String())
+	}
+	return instructions, nil
+}
+
+// builderImageDependency returns the builder image built from the Dockerfile
+// at the path, with the given ID, as a resolved dependency.
+func builderImageDependency(dockerfile, id string) (*slsa1.ResourceDescriptor, error) {
+	alg, value, ok := strings.Cut(id, ":")
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed image ID %q", errBuilderDockerfile, id)
+	}
+	return &slsa1.ResourceDescriptor{
+		Name:      dockerfile,
+		Digest:    map[string]string{alg: value},
+		MediaType: ociImageConfigMediaType,
+	}, nil
+}
+
+// buildBuilderImage builds the builder image from the Dockerfile, using the
+// root of the source repository as the build context. The image is recorded in
+// db, and its ID is returned.
+func buildBuilderImage(ctx context.Context, db *DockerBuild, rt ContainerRuntime, dockerfile string) (string, error) {
+	log.Printf("Building the builder image from %q.", dockerfile)
+	id, err := rt.BuildImage(ctx, dockerfile, ".")
+	if err != nil {
+		if cause := context.Cause(ctx); cause != nil {
+			return "", cause
+		}
+		return "", fmt.Errorf("%w: %w", errBuilderDockerfile, err)
+	}
+	if db.builtImage, err = builderImageDependency(dockerfile, id); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// BuildDependencies returns the resolved dependencies of the build that are
+// only known once BuildArtifacts has run, i.e., the builder image built from
+// the builder Dockerfile.
+func (db *DockerBuild) BuildDependencies() []slsa1.ResourceDescriptor {
+	if db.builtImage == nil {
+		return nil
+	}
+	return []slsa1.ResourceDescriptor{*db.builtImage}
+}
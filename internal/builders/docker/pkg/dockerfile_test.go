@@ -0,0 +1,295 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+func Test_dockerfileBaseImages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		dockerfile string
+		want       []DockerImage
+		err        error
+	}{
+		{
+			name:       "single stage",
+			dockerfile: "# Toolchain\nFROM golang@sha256:1111\nRUN go version\n",
+			want:       []DockerImage{{Name: "golang", Digest: Digest{Alg: "sha256", Value: "1111"}}},
+		},
+		{
+			name: "multi-stage",
+			dockerfile: "FROM --platform=linux/amd64 golang@sha256:1111 AS build\n" +
+				"from build as test\n" +
+				"FROM scratch\n" +
+				"COPY --from=build /go/bin /bin\n" +
+				"FROM gcr.io/distroless/base@sha256:2222\n",
+			want: []DockerImage{
+				{Name: "golang", Digest: Digest{Alg: "sha256", Value: "1111"}},
+				{Name: "gcr.io/distroless/base", Digest: Digest{Alg: "sha256", Value: "2222"}},
+			},
+		},
+		{
+			name: "copy from",
+			dockerfile: "FROM golang@sha256:1111 AS build\n" +
+				"FROM scratch\n" +
+				"COPY --from=build /go/bin /bin\n" +
+				"COPY --from=0 /etc/ssl /etc/ssl\n" +
+				"COPY --chown=0:0 --from=busybox@sha256:3333 /bin/sh /bin/sh\n" +
+				"COPY --from=busybox@sha256:3333 /bin/ls /bin/ls\n",
+			want: []DockerImage{
+				{Name: "golang", Digest: Digest{Alg: "sha256", Value: "1111"}},
+				{Name: "busybox", Digest: Digest{Alg: "sha256", Value: "3333"}},
+			},
+		},
+		{
+			name: "run mount from",
+			dockerfile: "FROM golang@sha256:1111 AS build\n" +
+				"RUN --mount=type=bind,from=build,target=/src true\n" +
+				"RUN --network=none --mount=type=bind,target=/tools,from=tools@sha256:4444 /tools/lint\n",
+			want: []DockerImage{
+				{Name: "golang", Digest: Digest{Alg: "sha256", Value: "1111"}},
+				{Name: "tools", Digest: Digest{Alg: "sha256", Value: "4444"}},
+			},
+		},
+		{
+			name: "line continuations",
+			dockerfile: "FROM \\\n  golang@sha256:1111 \\\n  AS build\n" +
+				"COPY \\\n# comment\n  --from=busybox@sha256:3333 /bin/sh /bin/sh\n",
+			want: []DockerImage{
+				{Name: "golang", Digest: Digest{Alg: "sha256", Value: "1111"}},
+				{Name: "busybox", Digest: Digest{Alg: "sha256", Value: "3333"}},
+			},
+		},
+		{
+			name:       "escape directive",
+			dockerfile: "# syntax=docker/dockerfile:1\n# escape=`\nFROM `\n  golang@sha256:1111\n",
+			want:       []DockerImage{{Name: "golang", Digest: Digest{Alg: "sha256", Value: "1111"}}},
+		},
+		{
+			name:       "not pinned",
+			dockerfile: "FROM golang:1.23\n",
+			err:        errBuilderDockerfile,
+		},
+		{
+			name:       "not pinned after a continuation",
+			dockerfile: "FROM golang@sha256:1111\nRUN true \\\n  && true\nFROM \\\n  golang:1.23\n",
+			err:        errBuilderDockerfile,
+		},
+		{
+			name:       "copy from not pinned",
+			dockerfile: "FROM golang@sha256:1111\nCOPY --from=busybox:latest /bin/sh /bin/sh\n",
+			err:        errBuilderDockerfile,
+		},
+		{
+			name:       "run mount from not pinned",
+			dockerfile: "FROM golang@sha256:1111\nRUN --mount=type=bind,from=tools,target=/tools /tools/lint\n",
+			err:        errBuilderDockerfile,
+		},
+		{
+			name:       "unknown stage index",
+			dockerfile: "FROM golang@sha256:1111\nCOPY --from=1 /bin /bin\n",
+			err:        errBuilderDockerfile,
+		},
+		{
+			name:       "variable",
+			dockerfile: "ARG IMG=golang@sha256:1111\nFROM ${IMG}\n",
+			err:        errBuilderDockerfile,
+		},
+		{
+			name:       "missing image",
+			dockerfile: "FROM --platform=linux/amd64\n",
+			err:        errBuilderDockerfile,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := filepath.Join(t.TempDir(), "Dockerfile")
+			if err := os.WriteFile(p, []byte(tt.dockerfile), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := dockerfileBaseImages(p)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.err, cmpopts.EquateErrors()))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected base images (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_checkBuilderImage(t *testing.T) {
+	t.Parallel()
+
+	image := DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}}
+	tests := []struct {
+		name       string
+		image      DockerImage
+		dockerfile string
+		wantErr    bool
+	}{
+		{
+			name:  "builder image",
+			image: image,
+		},
+		{
+			name:       "builder dockerfile",
+			dockerfile: "Dockerfile",
+		},
+		{
+			name:       "both",
+			image:      image,
+			dockerfile: "Dockerfile",
+			wantErr:    true,
+		},
+		{
+			name:    "neither",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkBuilderImage(&DockerBuildConfig{BuilderImage: tt.image}, &BuildConfig{BuilderDockerfile: tt.dockerfile})
+			if got := err != nil; got != tt.wantErr {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func Test_runDockerRun_builderDockerfile(t *testing.T) {
+	const codegenImage = "codegen@sha256:1111"
+
+	rt := &testRuntime{}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			SourceRepo:   "git+https://github.com/slsa-framework/slsa-github-generator",
+			SourceDigest: Digest{Alg: "sha1", Value: "cf5804b5c6f1a4b2a0b03401a487dfdfbe3a5f00"},
+		},
+		buildConfig: &BuildConfig{
+			BuilderDockerfile: "build/Dockerfile",
+			Steps: []BuildStep{
+				{Command: []string{"codegen"}, BuilderImage: codegenImage},
+				{Command: []string{"make"}},
+			},
+		},
+		baseImages: []DockerImage{{Name: "golang", Digest: Digest{Alg: "sha256", Value: "2222"}}},
+		runtime:    rt,
+	}
+	if err := runDockerRun(context.Background(), db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff([]string{"build/Dockerfile"}, rt.built); diff != "" {
+		t.Errorf("unexpected built images (-want +got):\n%s", diff)
+	}
+	var got []string
+	for _, c := range rt.commands {
+		got = append(got, c[3])
+	}
+	if diff := cmp.Diff([]string{codegenImage, builtImageID}, got); diff != "" {
+		t.Errorf("unexpected images (-want +got):\n%s", diff)
+	}
+
+	// The built image is a dependency of the build, in addition to the base
+	// images of the Dockerfile.
+	want := []slsa1.ResourceDescriptor{{
+		Name:      "build/Dockerfile",
+		Digest:    map[string]string{"sha256": "b1d7"},
+		MediaType: ociImageConfigMediaType,
+	}}
+	if diff := cmp.Diff(want, db.BuildDependencies()); diff != "" {
+		t.Errorf("unexpected build dependencies (-want +got):\n%s", diff)
+	}
+	bd := db.CreateBuildDefinition()
+	wantDeps := []slsa1.ResourceDescriptor{
+		{
+			URI:    "git+https://github.com/slsa-framework/slsa-github-generator",
+			Digest: map[string]string{"sha1": "cf5804b5c6f1a4b2a0b03401a487dfdfbe3a5f00"},
+		},
+		{URI: codegenImage, Digest: map[string]string{"sha256": "1111"}},
+		{URI: "golang@sha256:2222", Digest: map[string]string{"sha256": "2222"}},
+	}
+	if diff := cmp.Diff(wantDeps, bd.ResolvedDependencies); diff != "" {
+		t.Errorf("unexpected resolved dependencies (-want +got):\n%s", diff)
+	}
+	if ep := bd.ExternalParameters.(ContainerBasedExternalParameters); ep.BuilderImage.URI != "" {
+		t.Errorf("unexpected builder image: %q", ep.BuilderImage.URI)
+	}
+
+	// No step is run if the image cannot be built.
+	rt.commands = nil
+	rt.buildErr = errContainerRuntime
+	if err := runDockerRun(context.Background(), db); !errors.Is(err, errBuilderDockerfile) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errBuilderDockerfile, cmpopts.EquateErrors()))
+	}
+	if len(rt.commands) != 0 {
+		t.Errorf("unexpected commands: %q", rt.commands)
+	}
+}
+
+func Test_CreateBuildDefinition_dockerfileImages(t *testing.T) {
+	t.Parallel()
+
+	p := filepath.Join(t.TempDir(), "Dockerfile")
+	dockerfile := "FROM golang@sha256:1111 AS build\n" +
+		"COPY --from=busybox@sha256:2222 /bin/sh /bin/sh\n" +
+		"RUN --mount=type=bind,from=tools@sha256:3333,target=/tools /tools/lint\n"
+	if err := os.WriteFile(p, []byte(dockerfile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	images, err := dockerfileBaseImages(p)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			SourceRepo:   "git+https://github.com/slsa-framework/slsa-github-generator",
+			SourceDigest: Digest{Alg: "sha1", Value: "cf58"},
+		},
+		buildConfig: &BuildConfig{BuilderDockerfile: "Dockerfile", Command: []string{"make"}},
+		baseImages:  images,
+	}
+
+	// The images used by COPY and RUN are resolved dependencies, as the base
+	// images of the stages.
+	want := []slsa1.ResourceDescriptor{
+		{
+			URI:    "git+https://github.com/slsa-framework/slsa-github-generator",
+			Digest: map[string]string{"sha1": "cf58"},
+		},
+		{URI: "golang@sha256:1111", Digest: map[string]string{"sha256": "1111"}},
+		{URI: "busybox@sha256:2222", Digest: map[string]string{"sha256": "2222"}},
+		{URI: "tools@sha256:3333", Digest: map[string]string{"sha256": "3333"}},
+	}
+	if diff := cmp.Diff(want, db.CreateBuildDefinition().ResolvedDependencies); diff != "" {
+		t.Errorf("unexpected resolved dependencies (-want +got):\n%s", diff)
+	}
+}
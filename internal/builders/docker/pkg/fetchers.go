@@ -0,0 +1,576 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the Fetchers for sources that are available locally, so
+// that builds can run without access to the source repository: source
+// tarballs, Git bundles, and already checked out directories.
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1" //#nosec G505 -- Git object IDs are SHA-1 digests.
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/utils"
+)
+
+const (
+	// gitTreeAlg is the name of the digest of a local source directory. The
+	// digest is the ID of the Git tree object of the directory.
+	gitTreeAlg = "gitTree"
+
+	// gitBundleExtension is the extension of Git bundles.
+	gitBundleExtension = ".bundle"
+
+	// maxTarballSize is the default maximum size, in bytes, of the
+	// decompressed contents of a source tarball. The digest only bounds the
+	// size of the compressed tarball, so larger tarballs are rejected to
+	// avoid filling the disk when extracting a decompression bomb.
+	maxTarballSize int64 = 8 << 30
+)
+
+// tarballExtensions are the extensions of the supported source tarballs.
+var tarballExtensions = []string{".tar", ".tar.gz", ".tgz"}
+
+var (
+	// errSourceDigest indicates that the sources do not match the expected digest.
+	errSourceDigest = errors.New("source digest mismatch")
+
+	// errSourceArchive indicates an invalid or unsafe source tarball.
+	errSourceArchive = errors.New("source archive")
+)
+
+// newFetcher returns the Fetcher for the source repository of the config.
+// Sources with a `file` URI are fetched from a local tarball, Git bundle, or
+// directory, and the other sources are fetched from a remote Git repository.
+func newFetcher(config *DockerBuildConfig) (Fetcher, error) {
+	parsed, err := url.Parse(config.SourceRepo)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse repo URI: %v", err)
+	}
+	if parsed.Scheme != "file" {
+		return newGitClient(config, 0 /* depth */)
+	}
+
+	localPath := parsed.Path
+	if localPath == "" {
+		localPath = parsed.Opaque
+	}
+	switch {
+	case config.SourceDigest.Alg == gitTreeAlg:
+		return newLocalDirFetcher(localPath, config.SourceDigest, config.ForceCheckout)
+	case strings.HasSuffix(localPath, gitBundleExtension):
+		return newGitBundleClient(localPath, config)
+	case isTarball(localPath):
+		return newTarballFetcher(localPath, config.SourceDigest)
+	default:
+		return nil, fmt.Errorf("unsupported local source %q: want a directory with a %s digest, "+
+			"a Git bundle, or a tarball", localPath, gitTreeAlg)
+	}
+}
+
+// isTarball returns true if the path has the extension of a supported tarball.
+func isTarball(p string) bool {
+	for _, ext := range tarballExtensions {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// newGitBundleClient returns a GitClient that clones the repository from a
+// local Git bundle, and checks out the commit given by the source digest.
+func newGitBundleClient(bundlePath string, config *DockerBuildConfig) (*GitClient, error) {
+	// The bundle is cloned from a temporary directory.
+	absPath, err := filepath.Abs(bundlePath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the Git bundle path: %v", err)
+	}
+	return &GitClient{
+		sourceRepo:    &absPath,
+		sourceDigest:  &config.SourceDigest,
+		forceCheckout: config.ForceCheckout,
+		checkoutInfo:  &RepoCheckoutInfo{},
+		verbose:       config.Verbose,
+	}, nil
+}
+
+// TarballFetcher fetches the sources from a local tarball. The digest of the
+// tarball is verified before it is extracted into a temporary directory.
+type TarballFetcher struct {
+	path   string
+	digest Digest
+	// maxSize is the maximum size of the decompressed tarball. If it is zero,
+	// maxTarballSize is used.
+	maxSize int64
+}
+
+func newTarballFetcher(tarballPath string, digest Digest) (*TarballFetcher, error) {
+	if digest.Alg != utils.SHA256 && digest.Alg != utils.SHA512 {
+		return nil, fmt.Errorf("tarball digest must be a %s or %s digest", utils.SHA256, utils.SHA512)
+	}
+	absPath, err := filepath.Abs(tarballPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the tarball path: %v", err)
+	}
+	return &TarballFetcher{path: absPath, digest: digest}, nil
+}
+
+// Fetch is implemented for TarballFetcher to make it usable in contexts where
+// a Fetcher is needed. If all the files of the tarball are in a single
+// top-level directory, that directory is the root of the sources.
+func (f *TarballFetcher) Fetch() (*RepoCheckoutInfo, error) {
+	if err := verifyFileDigest(f.path, f.digest); err != nil {
+		return nil, err
+	}
+
+	targetDir, err := os.MkdirTemp("", "release-*")
+	if err != nil {
+		return nil, fmt.Errorf("couldn't create temp directory: %v", err)
+	}
+	info := &RepoCheckoutInfo{RepoRoot: targetDir}
+	log.Printf("Extracting the sources in %q.", targetDir)
+
+	if err := f.extract(targetDir); err != nil {
+		info.Cleanup()
+		return nil, err
+	}
+	if err := os.Chdir(targetDir); err != nil {
+		info.Cleanup()
+		return nil, fmt.Errorf("couldn't change directory to %q: %v", targetDir, err)
+	}
+	return info, nil
+}
+
+// verifyFileDigest checks that the file at the path has the expected digest.
+func verifyFileDigest(p string, digest Digest) error {
+	file, err := os.Open(p)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", p, err)
+	}
+	defer file.Close()
+
+	digests, err := utils.HashReader(nil, file, digest.Alg)
+	if err != nil {
+		return fmt.Errorf("hashing %q: %w", p, err)
+	}
+	if got := digests[digest.Alg]; got != digest.Value {
+		return fmt.Errorf("%w: %q has digest %s:%s, want %s:%s",
+			errSourceDigest, p, digest.Alg, got, digest.Alg, digest.Value)
+	}
+	return nil
+}
+
+// forEachEntry calls fn with each entry of the tarball, except the pax global
+// headers, such as the ones added by `git archive`. It fails if the
+// decompressed tarball is larger than the maximum size of the fetcher.
+func (f *TarballFetcher) forEachEntry(fn func(*tar.Header, io.Reader) error) error {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return fmt.Errorf("opening %q: %w", f.path, err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(f.path, ".gz") || strings.HasSuffix(f.path, ".tgz") {
+		gr, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("%w: %v", errSourceArchive, err)
+		}
+		defer gr.Close()
+		r = gr
+	}
+
+	maxSize := f.maxSize
+	if maxSize == 0 {
+		maxSize = maxTarballSize
+	}
+	// Reading one more byte than the maximum tells that the limit was hit.
+	lr := &io.LimitedReader{R: r, N: maxSize + 1}
+	tooLarge := func() error {
+		return fmt.Errorf("%w: %q is larger than %d bytes when decompressed", errSourceArchive, f.path, maxSize)
+	}
+
+	tr := tar.NewReader(lr)
+	for {
+		hdr, err := tr.Next()
+		if lr.N <= 0 {
+			return tooLarge()
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", errSourceArchive, err)
+		}
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		if err := fn(hdr, tr); err != nil {
+			if lr.N <= 0 {
+				return tooLarge()
+			}
+			return err
+		}
+	}
+}
+
+// entryName returns the cleaned, slash-separated name of the entry.
+func entryName(hdr *tar.Header) string {
+	return strings.TrimPrefix(path.Clean(filepath.ToSlash(hdr.Name)), "./")
+}
+
+// topLevelDir returns the top-level directory containing all the entries of
+// the tarball, with a trailing slash, or an empty string if there is none.
+func (f *TarballFetcher) topLevelDir() (string, error) {
+	var dir string
+	err := f.forEachEntry(func(hdr *tar.Header, _ io.Reader) error {
+		name := entryName(hdr)
+		if name == "." {
+			return nil
+		}
+		top, _, nested := strings.Cut(name, "/")
+		if (!nested && hdr.Typeflag != tar.TypeDir) || (dir != "" && dir != top) {
+			return errNoTopLevelDir
+		}
+		dir = top
+		return nil
+	})
+	if errors.Is(err, errNoTopLevelDir) || dir == "" {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return dir + "/", nil
+}
+
+// errNoTopLevelDir is used by topLevelDir to stop iterating over the entries.
+var errNoTopLevelDir = errors.New("no top-level directory")
+
+// extract extracts the tarball into the directory. It fails if an entry is
+// not a directory, regular file, or symbolic link, or if it would be
+// extracted out of the directory. Symbolic links must be relative and must
+// not contain `..`.
+func (f *TarballFetcher) extract(dir string) error {
+	prefix, err := f.topLevelDir()
+	if err != nil {
+		return err
+	}
+
+	return f.forEachEntry(func(hdr *tar.Header, r io.Reader) error {
+		name := entryName(hdr)
+		if name == "." || name+"/" == prefix {
+			return nil
+		}
+		name = strings.TrimPrefix(name, prefix)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("%w: entry %q is outside of the archive", errSourceArchive, hdr.Name)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(name))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return fmt.Errorf("creating directory %q: %w", name, err)
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("creating directory of %q: %w", name, err)
+			}
+			var mode fs.FileMode = 0o644
+			if hdr.Mode&0o111 != 0 {
+				mode = 0o755
+			}
+			// Never write through an existing file or symbolic link.
+			out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+			if err != nil {
+				return fmt.Errorf("%w: creating %q: %v", errSourceArchive, name, err)
+			}
+			//#nosec G110 -- forEachEntry limits the decompressed size of the tarball.
+			_, err = io.Copy(out, r)
+			if cerr := out.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return fmt.Errorf("writing %q: %w", name, err)
+			}
+		case tar.TypeSymlink:
+			link := filepath.ToSlash(hdr.Linkname)
+			if path.IsAbs(link) || containsDotDot(link) {
+				return fmt.Errorf("%w: symbolic link %q points to %q", errSourceArchive, hdr.Name, hdr.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return fmt.Errorf("creating directory of %q: %w", name, err)
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return fmt.Errorf("%w: creating %q: %v", errSourceArchive, name, err)
+			}
+		default:
+			return fmt.Errorf("%w: entry %q has unsupported type %q", errSourceArchive, hdr.Name, hdr.Typeflag)
+		}
+		return nil
+	})
+}
+
+// containsDotDot returns true if any segment of the slash-separated path is `..`.
+func containsDotDot(p string) bool {
+	for _, segment := range strings.Split(p, "/") {
+		if segment == ".." {
+			return true
+		}
+	}
+	return false
+}
+
+// LocalDirFetcher uses the sources in a local directory, e.g., one that is
+// already checked out. The Git tree hash of the directory is verified, and
+// the directory is never removed. If forceCheckout is set, the build uses a
+// copy of the directory in a temporary directory instead.
+type LocalDirFetcher struct {
+	dir           string
+	digest        Digest
+	forceCheckout bool
+}
+
+func newLocalDirFetcher(dir string, digest Digest, forceCheckout bool) (*LocalDirFetcher, error) {
+	if digest.Alg != gitTreeAlg {
+		return nil, fmt.Errorf("directory digest must be a %s digest", gitTreeAlg)
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve the source directory: %v", err)
+	}
+	return &LocalDirFetcher{dir: absDir, digest: digest, forceCheckout: forceCheckout}, nil
+}
+
+// Fetch is implemented for LocalDirFetcher to make it usable in contexts where
+// a Fetcher is needed.
+func (f *LocalDirFetcher) Fetch() (*RepoCheckoutInfo, error) {
+	// The RepoRoot is left empty, so that the directory is not cleaned up.
+	info := &RepoCheckoutInfo{}
+	dir := f.dir
+	if f.forceCheckout {
+		targetDir, err := os.MkdirTemp("", "release-*")
+		if err != nil {
+			return nil, fmt.Errorf("couldn't create temp directory: %v", err)
+		}
+		info.RepoRoot = targetDir
+		log.Printf("Copying the sources to %q.", targetDir)
+		if err := copyDir(f.dir, targetDir); err != nil {
+			info.Cleanup()
+			return nil, fmt.Errorf("couldn't copy the sources: %w", err)
+		}
+		dir = targetDir
+	}
+
+	// The copy is verified rather than the original directory, so that
+	// changes made while copying are detected.
+	got, err := gitTreeHash(dir)
+	if err == nil && got != f.digest.Value {
+		err = fmt.Errorf("%w: %q has digest %s:%s, want %s:%s",
+			errSourceDigest, f.dir, gitTreeAlg, got, gitTreeAlg, f.digest.Value)
+	}
+	if err == nil {
+		if err = os.Chdir(dir); err != nil {
+			err = fmt.Errorf("couldn't change directory to %q: %v", dir, err)
+		}
+	}
+	if err != nil {
+		info.Cleanup()
+		return nil, err
+	}
+	return info, nil
+}
+
+// copyDir copies the directories, regular files and symbolic links under src
+// to dst, excluding `.git` directories, which are not part of the Git tree.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir() && d.Name() == ".git":
+			return filepath.SkipDir
+		case d.IsDir():
+			return os.MkdirAll(target, 0o755)
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(p, target)
+		default:
+			return fmt.Errorf("%q has unsupported file type %v", p, d.Type())
+		}
+	})
+}
+
+// copyFile copies the regular file at src to dst, preserving its executable
+// bits.
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var mode fs.FileMode = 0o644
+	if info.Mode()&0o111 != 0 {
+		mode = 0o755
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// gitTreeHash returns the ID of the Git tree object of the files in the
+// directory, excluding `.git`. It is equal to the output of
+// `git rev-parse HEAD^{tree}` if all the files of the directory are committed,
+// and there are no untracked files.
+func gitTreeHash(dir string) (string, error) {
+	sum, _, err := gitTree(dir)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum), nil
+}
+
+// gitTree returns the ID of the Git tree object of the directory, and whether
+// the tree is empty. Empty trees are omitted from their parent, like in Git.
+func gitTree(dir string) ([]byte, bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, false, fmt.Errorf("reading directory %q: %w", dir, err)
+	}
+
+	type treeEntry struct {
+		mode, name, sortKey string
+		sum                 []byte
+	}
+	var tree []treeEntry
+	for _, e := range entries {
+		if e.Name() == ".git" {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		entry := treeEntry{name: e.Name(), sortKey: e.Name()}
+		switch {
+		case e.Type()&fs.ModeSymlink != 0:
+			target, err := os.Readlink(p)
+			if err != nil {
+				return nil, false, fmt.Errorf("reading symbolic link %q: %w", p, err)
+			}
+			entry.mode = "120000"
+			entry.sum = gitObjectHash("blob", []byte(filepath.ToSlash(target)))
+		case e.IsDir():
+			sum, empty, err := gitTree(p)
+			if err != nil {
+				return nil, false, err
+			}
+			if empty {
+				continue
+			}
+			// Git sorts directories as if their names ended with a slash.
+			entry.mode, entry.sortKey, entry.sum = "40000", e.Name()+"/", sum
+		case e.Type().IsRegular():
+			info, err := e.Info()
+			if err != nil {
+				return nil, false, fmt.Errorf("reading file info of %q: %w", p, err)
+			}
+			entry.mode = "100644"
+			if info.Mode()&0o111 != 0 {
+				entry.mode = "100755"
+			}
+			if entry.sum, err = gitBlobHash(p, info.Size()); err != nil {
+				return nil, false, err
+			}
+		default:
+			return nil, false, fmt.Errorf("%q has unsupported file type %v", p, e.Type())
+		}
+		tree = append(tree, entry)
+	}
+
+	sort.Slice(tree, func(i, j int) bool { return tree[i].sortKey < tree[j].sortKey })
+	var content bytes.Buffer
+	for _, entry := range tree {
+		fmt.Fprintf(&content, "%s %s\x00", entry.mode, entry.name)
+		content.Write(entry.sum)
+	}
+	return gitObjectHash("tree", content.Bytes()), len(tree) == 0, nil
+}
+
+// gitObjectHash returns the ID of the Git object of the type with the content.
+func gitObjectHash(objectType string, content []byte) []byte {
+	//#nosec G401 -- Git object IDs are SHA-1 digests.
+	h := sha1.New()
+	fmt.Fprintf(h, "%s %d\x00", objectType, len(content))
+	h.Write(content)
+	return h.Sum(nil)
+}
+
+// gitBlobHash returns the ID of the Git blob object of the file, which is
+// streamed rather than read into memory.
+func gitBlobHash(p string, size int64) ([]byte, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		return nil, fmt.Errorf("opening %q: %w", p, err)
+	}
+	defer file.Close()
+
+	//#nosec G401 -- Git object IDs are SHA-1 digests.
+	h := sha1.New()
+	fmt.Fprintf(h, "blob %d\x00", size)
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return nil, fmt.Errorf("hashing %q: %w", p, err)
+	}
+	if n != size {
+		return nil, fmt.Errorf("%q changed while being hashed", p)
+	}
+	return h.Sum(nil), nil
+}
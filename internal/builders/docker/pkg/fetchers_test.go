@@ -0,0 +1,479 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+// chdirTemp changes the working directory to a new temporary directory, and
+// restores it when the test ends.
+func chdirTemp(t *testing.T) string {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// runGit runs a git command in the directory.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	args = append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+	out, err := exec.Command("git", args...).Output()
+	if err != nil {
+		t.Fatalf("git %v: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// writeFiles creates the files with their names as content under the directory.
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// writeTarball writes a gzipped tarball with the entries, and returns its
+// SHA256 digest.
+func writeTarball(t *testing.T, p string, entries []*tar.Header) string {
+	t.Helper()
+	f, err := os.Create(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gw)
+	for _, hdr := range entries {
+		content := ""
+		if hdr.Typeflag == tar.TypeReg {
+			content = hdr.Name
+			hdr.Size = int64(len(content))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range []interface{ Close() error }{tw, gw, f} {
+		if err := c.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func Test_newFetcher(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		repo    string
+		digest  Digest
+		want    Fetcher
+		wantErr bool
+	}{
+		{
+			name:   "git repo",
+			repo:   "git+https://github.com/slsa-framework/slsa-github-generator",
+			digest: Digest{Alg: "sha1", Value: "abcd"},
+			want:   &GitClient{},
+		},
+		{
+			name:   "git bundle",
+			repo:   "file:///tmp/repo.bundle",
+			digest: Digest{Alg: "sha1", Value: "abcd"},
+			want:   &GitClient{},
+		},
+		{
+			name:   "tarball",
+			repo:   "file:///tmp/source.tar.gz",
+			digest: Digest{Alg: "sha256", Value: "abcd"},
+			want:   &TarballFetcher{path: "/tmp/source.tar.gz", digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		{
+			name:   "local directory",
+			repo:   "file:///tmp/source",
+			digest: Digest{Alg: gitTreeAlg, Value: "abcd"},
+			want:   &LocalDirFetcher{dir: "/tmp/source", digest: Digest{Alg: gitTreeAlg, Value: "abcd"}},
+		},
+		{
+			name:    "tarball with a sha1 digest",
+			repo:    "file:///tmp/source.tgz",
+			digest:  Digest{Alg: "sha1", Value: "abcd"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown local source",
+			repo:    "file:///tmp/source.zip",
+			digest:  Digest{Alg: "sha256", Value: "abcd"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := newFetcher(&DockerBuildConfig{SourceRepo: tt.repo, SourceDigest: tt.digest})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if _, ok := tt.want.(*GitClient); ok {
+				if _, ok := got.(*GitClient); !ok {
+					t.Errorf("unexpected fetcher type %T", got)
+				}
+				return
+			}
+			if diff := cmp.Diff(tt.want, got, cmp.AllowUnexported(TarballFetcher{}, LocalDirFetcher{})); diff != "" {
+				t.Errorf("unexpected fetcher (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_gitTreeHash(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	writeFiles(t, dir, "README.md", "src/main.go", "src/lib/lib.go", "src.go", "src-a/b")
+	if err := os.Chmod(filepath.Join(dir, "src.go"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("src/main.go", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	// Empty directories are not part of the Git tree.
+	if err := os.Mkdir(filepath.Join(dir, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	runGit(t, dir, "init", "-q")
+	runGit(t, dir, "add", "-A")
+	want := runGit(t, dir, "write-tree")
+
+	got, err := gitTreeHash(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != want {
+		t.Errorf("unexpected tree hash, got: %s, want: %s", got, want)
+	}
+}
+
+func Test_LocalDirFetcher(t *testing.T) {
+	dir := chdirTemp(t)
+	src := filepath.Join(dir, "src")
+	writeFiles(t, src, "main.go")
+	hash, err := gitTreeHash(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := newLocalDirFetcher("src", Digest{Alg: gitTreeAlg, Value: "0000"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Fetch(); !errors.Is(err, errSourceDigest) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errSourceDigest, cmpopts.EquateErrors()))
+	}
+
+	f.digest.Value = hash
+	info, err := f.Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The directory must not be removed by Cleanup.
+	info.Cleanup()
+	if _, err := os.Stat("main.go"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// With forceCheckout, a copy of the directory is used, without `.git`.
+	writeFiles(t, src, ".git/HEAD", "cmd/tool/main.go")
+	if f.digest.Value, err = gitTreeHash(src); err != nil {
+		t.Fatal(err)
+	}
+	f.forceCheckout = true
+	info, err = f.Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.RepoRoot == "" {
+		t.Fatal("the copy has no RepoRoot")
+	}
+	for _, name := range []string{"main.go", "cmd/tool/main.go"} {
+		if _, err := os.Stat(filepath.Join(info.RepoRoot, name)); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(info.RepoRoot, ".git")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf(".git was copied: %v", err)
+	}
+	info.Cleanup()
+	if _, err := os.Stat(filepath.Join(src, "main.go")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func Test_TarballFetcher(t *testing.T) {
+	dir := chdirTemp(t)
+
+	tests := []struct {
+		name      string
+		entries   []*tar.Header
+		wantFiles map[string]string
+		expected  error
+	}{
+		{
+			name: "single top-level directory",
+			entries: []*tar.Header{
+				{Name: "pax_global_header", Typeflag: tar.TypeXGlobalHeader, PAXRecords: map[string]string{"comment": "abcd"}},
+				{Name: "repo-1.0/", Typeflag: tar.TypeDir, Mode: 0o755},
+				{Name: "repo-1.0/README.md", Typeflag: tar.TypeReg, Mode: 0o644},
+				{Name: "repo-1.0/src/main.go", Typeflag: tar.TypeReg, Mode: 0o644},
+				{Name: "repo-1.0/link", Typeflag: tar.TypeSymlink, Linkname: "src/main.go"},
+			},
+			wantFiles: map[string]string{
+				"README.md":   "repo-1.0/README.md",
+				"src/main.go": "repo-1.0/src/main.go",
+				"link":        "repo-1.0/src/main.go",
+			},
+		},
+		{
+			name: "multiple top-level entries",
+			entries: []*tar.Header{
+				{Name: "./README.md", Typeflag: tar.TypeReg, Mode: 0o644},
+				{Name: "./src/main.go", Typeflag: tar.TypeReg, Mode: 0o755},
+			},
+			wantFiles: map[string]string{"README.md": "./README.md", "src/main.go": "./src/main.go"},
+		},
+		{
+			name: "entry outside of the archive",
+			entries: []*tar.Header{
+				{Name: "README.md", Typeflag: tar.TypeReg, Mode: 0o644},
+				{Name: "../evil", Typeflag: tar.TypeReg, Mode: 0o644},
+			},
+			expected: errSourceArchive,
+		},
+		{
+			name: "symbolic link out of the archive",
+			entries: []*tar.Header{
+				{Name: "sub/link", Typeflag: tar.TypeSymlink, Linkname: "../../etc/passwd"},
+				{Name: "README.md", Typeflag: tar.TypeReg, Mode: 0o644},
+			},
+			expected: errSourceArchive,
+		},
+		{
+			name: "file written through a symbolic link",
+			entries: []*tar.Header{
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "README.md"},
+				{Name: "link", Typeflag: tar.TypeReg, Mode: 0o644},
+			},
+			expected: errSourceArchive,
+		},
+		{
+			name: "hard link",
+			entries: []*tar.Header{
+				{Name: "README.md", Typeflag: tar.TypeReg, Mode: 0o644},
+				{Name: "link", Typeflag: tar.TypeLink, Linkname: "README.md"},
+			},
+			expected: errSourceArchive,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			tarball := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "-")+".tar.gz")
+			digest := writeTarball(t, tarball, tt.entries)
+
+			f, err := newTarballFetcher(tarball, Digest{Alg: "sha256", Value: digest})
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := f.Fetch()
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if err != nil {
+				return
+			}
+			defer info.Cleanup()
+
+			for name, want := range tt.wantFiles {
+				content, err := os.ReadFile(filepath.Join(info.RepoRoot, filepath.FromSlash(name)))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(content) != want {
+					t.Errorf("unexpected content of %q, got: %q, want: %q", name, content, want)
+				}
+			}
+			if cwd, err := os.Getwd(); err != nil || cwd != info.RepoRoot {
+				t.Errorf("unexpected working directory %q, want: %q", cwd, info.RepoRoot)
+			}
+		})
+	}
+
+	t.Run("digest mismatch", func(t *testing.T) {
+		tarball := filepath.Join(dir, "mismatch.tar.gz")
+		writeTarball(t, tarball, []*tar.Header{{Name: "README.md", Typeflag: tar.TypeReg, Mode: 0o644}})
+		f, err := newTarballFetcher(tarball, Digest{Alg: "sha256", Value: "0000"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Fetch(); !errors.Is(err, errSourceDigest) {
+			t.Errorf("unexpected error: %v", cmp.Diff(err, errSourceDigest, cmpopts.EquateErrors()))
+		}
+	})
+
+	t.Run("too large when decompressed", func(t *testing.T) {
+		tarball := filepath.Join(dir, "large.tar.gz")
+		digest := writeTarball(t, tarball, []*tar.Header{
+			{Name: "README.md", Typeflag: tar.TypeReg, Mode: 0o644},
+			{Name: "src/main.go", Typeflag: tar.TypeReg, Mode: 0o644},
+		})
+		f, err := newTarballFetcher(tarball, Digest{Alg: "sha256", Value: digest})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Each entry takes a 512-byte header and a 512-byte data block.
+		f.maxSize = 1024
+		if _, err := f.Fetch(); !errors.Is(err, errSourceArchive) {
+			t.Errorf("unexpected error: %v", cmp.Diff(err, errSourceArchive, cmpopts.EquateErrors()))
+		}
+	})
+}
+
+func Test_GitClient_bundle(t *testing.T) {
+	dir := chdirTemp(t)
+	repo := filepath.Join(dir, "repo")
+	writeFiles(t, repo, "README.md")
+	runGit(t, dir, "init", "-q", repo)
+	runGit(t, repo, "add", "-A")
+	runGit(t, repo, "commit", "-q", "-m", "initial")
+	commit := runGit(t, repo, "rev-parse", "HEAD")
+	runGit(t, repo, "bundle", "create", "-q", filepath.Join(dir, "repo.bundle"), "--all")
+
+	config := &DockerBuildConfig{
+		SourceRepo:   "file://" + filepath.ToSlash(filepath.Join(dir, "repo.bundle")),
+		SourceDigest: Digest{Alg: "sha1", Value: commit},
+	}
+	f, err := newFetcher(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gc := f.(*GitClient)
+	defer gc.cleanupAllFiles()
+
+	info, err := gc.Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(info.RepoRoot, "README.md")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	want := slsa1.ResourceDescriptor{URI: config.SourceRepo, Digest: map[string]string{"sha1": commit}}
+	if diff := cmp.Diff(want, sourceArtifact(config)); diff != "" {
+		t.Errorf("unexpected source artifact (-want +got):\n%s", diff)
+	}
+}
+
+func Test_sourceDigest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		digests map[string]string
+		want    *Digest
+		wantErr bool
+	}{
+		{
+			name:    "git commit",
+			digests: map[string]string{"sha1": "abcd"},
+			want:    &Digest{Alg: "sha1", Value: "abcd"},
+		},
+		{
+			name:    "git tree",
+			digests: map[string]string{gitTreeAlg: "abcd"},
+			want:    &Digest{Alg: gitTreeAlg, Value: "abcd"},
+		},
+		{
+			name:    "tarball",
+			digests: map[string]string{"sha512": "ef01", "sha256": "abcd"},
+			want:    &Digest{Alg: "sha256", Value: "abcd"},
+		},
+		{
+			name:    "unsupported",
+			digests: map[string]string{"md5": "abcd"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := sourceDigest(tt.digests)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected digest (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the matching of artifact paths against glob patterns
+// that support `**`.
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// recursiveWildcard is the path segment that matches zero or more directories.
+const recursiveWildcard = "**"
+
+// validatePattern checks that the pattern is well-formed.
+func validatePattern(pattern string) error {
+	for _, segment := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if segment == recursiveWildcard {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("the pattern (%q) is malformed: %v", pattern, err)
+		}
+	}
+	return nil
+}
+
+// isRecursive returns true if the pattern contains a `**` path segment.
+func isRecursive(pattern string) bool {
+	for _, segment := range strings.Split(filepath.ToSlash(pattern), "/") {
+		if segment == recursiveWildcard {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath returns true if the slash-separated name matches the pattern. A
+// `**` segment in the pattern matches zero or more segments of the name, and
+// the other segments are matched using path.Match.
+func matchPath(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(path.Clean(filepath.ToSlash(pattern)), "/"),
+		strings.Split(path.Clean(filepath.ToSlash(name)), "/"))
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == recursiveWildcard {
+			for i := 0; i <= len(name); i++ {
+				if ok, err := matchSegments(pattern[1:], name[i:]); ok || err != nil {
+					return ok, err
+				}
+			}
+			return false, nil
+		}
+		if len(name) == 0 {
+			return false, nil
+		}
+		ok, err := path.Match(pattern[0], name[0])
+		if err != nil || !ok {
+			return false, err
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0, nil
+}
+
+// glob returns the paths matching the pattern. Patterns without a `**`
+// segment are matched using filepath.Glob. Patterns with a `**` segment only
+// match regular files, which are found by walking the directory given by the
+// segments of the pattern that precede the first wildcard.
+func glob(pattern string) ([]string, error) {
+	if err := validatePattern(pattern); err != nil {
+		return nil, err
+	}
+	if !isRecursive(pattern) {
+		// The only possible error is ErrBadPattern, which is checked above.
+		return filepath.Glob(pattern)
+	}
+
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	var base []string
+	for _, segment := range strings.Split(pattern, "/") {
+		if strings.ContainsAny(segment, `*?[\`) {
+			break
+		}
+		base = append(base, segment)
+	}
+	root := "."
+	if len(base) > 0 {
+		root = filepath.FromSlash(strings.Join(base, "/"))
+		if strings.HasPrefix(pattern, "/") {
+			root = string(filepath.Separator) + root
+		}
+	}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		ok, err := matchPath(pattern, p)
+		if err != nil {
+			return err
+		}
+		if ok {
+			matches = append(matches, p)
+		}
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("matching the pattern %q: %w", pattern, err)
+	}
+	return matches, nil
+}
+
+// findArtifacts returns the sorted and deduplicated paths matching any of the
+// patterns and none of the excludes.
+func findArtifacts(patterns, excludes []string) ([]string, error) {
+	seen := map[string]bool{}
+	var matches []string
+	for _, pattern := range patterns {
+		paths, err := glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			excluded, err := isExcluded(p, excludes)
+			if err != nil {
+				return nil, err
+			}
+			if excluded || seen[p] {
+				continue
+			}
+			seen[p] = true
+			matches = append(matches, p)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// isExcluded returns true if the path matches any of the excludes.
+func isExcluded(p string, excludes []string) (bool, error) {
+	for _, exclude := range excludes {
+		ok, err := matchPath(exclude, p)
+		if err != nil {
+			return false, fmt.Errorf("the exclude pattern (%q) is malformed: %v", exclude, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,153 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_matchPath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+		wantErr bool
+	}{
+		{pattern: "target/*/release/app", name: "target/x86_64/release/app", want: true},
+		{pattern: "target/*/release/app", name: "target/a/b/release/app"},
+		{pattern: "target/**/app", name: "target/app", want: true},
+		{pattern: "target/**/app", name: "target/a/b/release/app", want: true},
+		{pattern: "target/**", name: "target/a/b", want: true},
+		{pattern: "**/*.tar.gz", name: "dist/linux/app.tar.gz", want: true},
+		{pattern: "**/*.tar.gz", name: "app.tar.gz", want: true},
+		{pattern: "**/*.tar.gz", name: "dist/app.zip"},
+		{pattern: "./dist/**", name: "dist/app", want: true},
+		{pattern: "dist/[", name: "dist/app", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := matchPath(tt.pattern, tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected match, got: %t, want: %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_findArtifacts(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{
+		"target/x86_64/release/app",
+		"target/x86_64/release/app.d",
+		"target/aarch64/release/app",
+		"target/aarch64/release/build/dep/out",
+		"target/debug/app",
+		"README.md",
+	} {
+		if err := os.MkdirAll(filepath.Dir(f), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte(f), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		excludes []string
+		want     []string
+		wantErr  bool
+	}{
+		{
+			name:     "single level wildcard",
+			patterns: []string{"target/*/release/app"},
+			want:     []string{"target/aarch64/release/app", "target/x86_64/release/app"},
+		},
+		{
+			name:     "recursive with excludes",
+			patterns: []string{"target/**"},
+			excludes: []string{"**/*.d", "target/*/release/build/**", "target/debug/**"},
+			want:     []string{"target/aarch64/release/app", "target/x86_64/release/app"},
+		},
+		{
+			name:     "multiple patterns deduplicated",
+			patterns: []string{"target/*/release/app", "target/**/app", "*.md"},
+			want: []string{
+				"README.md",
+				"target/aarch64/release/app",
+				"target/debug/app",
+				"target/x86_64/release/app",
+			},
+		},
+		{
+			name:     "missing directory",
+			patterns: []string{"dist/**"},
+		},
+		{
+			name:     "malformed pattern",
+			patterns: []string{"target/**/["},
+			wantErr:  true,
+		},
+		{
+			name:     "malformed exclude",
+			patterns: []string{"target/**"},
+			excludes: []string{"["},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := findArtifacts(tt.patterns, tt.excludes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected matches (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	bc := &BuildConfig{ArtifactPaths: []string{"dist/**", "target/**/app"}}
+	if err := checkExistingArtifacts(bc); err == nil {
+		t.Errorf("expected an error for existing artifacts")
+	}
+	bc.ArtifactExcludes = []string{"target/**"}
+	if err := checkExistingArtifacts(bc); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,387 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the resolution of the builder images to local images,
+// whose digests are verified before running the build steps. The images are
+// either pulled from their registries, or loaded from an OCI image layout.
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/utils"
+)
+
+var (
+	// errImageDigest indicates that a local image does not match the digest
+	// of the builder image.
+	errImageDigest = errors.New("builder image digest")
+
+	// errImageLayout indicates an invalid OCI image layout.
+	errImageLayout = errors.New("OCI image layout")
+)
+
+// ImageInfo describes a local container image.
+type ImageInfo struct {
+	// ID of the local image.
+	ID string `json:"Id"`
+
+	// RepoDigests are the references to the image by the digests of its
+	// manifests in registries, in the form NAME@ALG:VALUE.
+	RepoDigests []string `json:"RepoDigests"`
+}
+
+// imageResolver resolves the builder images to the IDs of local images whose
+// digests are verified.
+type imageResolver struct {
+	runtime ContainerRuntime
+	// OCI image layout the images are loaded from, if any.
+	layout *ociLayout
+	// Whether the layout has been loaded into the runtime.
+	loaded   bool
+	resolved map[string]string
+}
+
+func newImageResolver(rt ContainerRuntime, layout *ociLayout) *imageResolver {
+	return &imageResolver{
+		runtime:  rt,
+		layout:   layout,
+		resolved: map[string]string{},
+	}
+}
+
+// resolve returns the ID of the local image of the builder image, given in
+// the form NAME@ALG:VALUE. The image is loaded from the OCI image layout if
+// it contains the digest, and pulled from its registry otherwise. The pull or
+// load is stopped if ctx is done before it completes.
+func (r *imageResolver) resolve(ctx context.Context, image string) (string, error) {
+	if id, ok := r.resolved[image]; ok {
+		return id, nil
+	}
+	di, err := validateDockerImage(image)
+	if err != nil {
+		return "", err
+	}
+
+	var id string
+	if r.layout != nil && r.layout.contains(di.Digest) {
+		id, err = r.resolveFromLayout(ctx, di)
+	} else {
+		id, err = r.resolveFromRuntime(ctx, di)
+	}
+	if err != nil {
+		return "", err
+	}
+	log.Printf("Verified the builder image %q (%s).", image, id)
+	r.resolved[image] = id
+	return id, nil
+}
+
+// resolveFromRuntime returns the ID of the local image of the builder image,
+// after pulling it if it is not available locally. It fails if none of the
+// repo digests of the local image is the digest of the builder image.
+func (r *imageResolver) resolveFromRuntime(ctx context.Context, di *DockerImage) (string, error) {
+	ref := di.ToString()
+	info, err := r.runtime.InspectImage(ctx, ref)
+	if err != nil {
+		if err := r.runtime.PullImage(ctx, ref); err != nil {
+			return "", fmt.Errorf("%w: pulling %q: %w", errImageDigest, ref, err)
+		}
+		if info, err = r.runtime.InspectImage(ctx, ref); err != nil {
+			return "", fmt.Errorf("%w: inspecting %q: %w", errImageDigest, ref, err)
+		}
+	}
+
+	want := fmt.Sprintf("@%s:%s", di.Digest.Alg, di.Digest.Value)
+	for _, rd := range info.RepoDigests {
+		if strings.HasSuffix(rd, want) {
+			return info.ID, nil
+		}
+	}
+	return "", fmt.Errorf("%w: the local image %q of %q has repo digests %q",
+		errImageDigest, info.ID, ref, info.RepoDigests)
+}
+
+// resolveFromLayout verifies the builder image in the OCI image layout, loads
+// the layout into the runtime, and returns the ID of the loaded image.
+func (r *imageResolver) resolveFromLayout(ctx context.Context, di *DockerImage) (string, error) {
+	ids, err := r.layout.verify(di.Digest)
+	if err != nil {
+		return "", err
+	}
+	if !r.loaded {
+		if err := r.layout.load(ctx, r.runtime); err != nil {
+			return "", err
+		}
+		r.loaded = true
+	}
+
+	// Depending on the image store of the runtime, the ID of the image is
+	// either the digest of its config or of its manifest.
+	for _, id := range ids {
+		if info, err := r.runtime.InspectImage(ctx, id); err == nil && info.ID == id {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("%w: the image %q is not available after loading the layout",
+		errImageLayout, di.ToString())
+}
+
+const (
+	// ociLayoutIndex is the name of the index of an OCI image layout.
+	ociLayoutIndex = "index.json"
+
+	// ociLayoutBlobs is the directory of the blobs of an OCI image layout.
+	ociLayoutBlobs = "blobs"
+)
+
+// ociDescriptor is an OCI content descriptor.
+type ociDescriptor struct {
+	MediaType string       `json:"mediaType"`
+	Digest    string       `json:"digest"`
+	Size      int64        `json:"size"`
+	Platform  *ociPlatform `json:"platform,omitempty"`
+}
+
+// ociPlatform is the platform of an image in an image index.
+type ociPlatform struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+}
+
+// ociIndex is an OCI image index, or a Docker manifest list.
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest is an OCI image manifest, or a Docker image manifest.
+type ociManifest struct {
+	Config *ociDescriptor  `json:"config"`
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// ociLayout is an OCI image layout directory.
+type ociLayout struct {
+	dir   string
+	index ociIndex
+}
+
+// openOCILayout opens the OCI image layout in the directory or tarball at the
+// path. Tarballs are extracted into a temporary directory, which is removed
+// by the returned cleanup function.
+func openOCILayout(p string) (*ociLayout, func(), error) {
+	cleanup := func() {}
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, cleanup, fmt.Errorf("%w: %w", errImageLayout, err)
+	}
+
+	dir := p
+	if !info.IsDir() {
+		if dir, err = os.MkdirTemp("", "oci-layout-*"); err != nil {
+			return nil, cleanup, fmt.Errorf("couldn't create temp directory: %v", err)
+		}
+		cleanup = func() {
+			if err := os.RemoveAll(dir); err != nil {
+				log.Printf("failed to remove the temp files: %v", err)
+			}
+		}
+		absPath, err := filepath.Abs(p)
+		if err != nil {
+			return nil, cleanup, fmt.Errorf("could not resolve the layout path: %v", err)
+		}
+		if err := (&TarballFetcher{path: absPath}).extract(dir); err != nil {
+			return nil, cleanup, fmt.Errorf("%w: extracting %q: %w", errImageLayout, p, err)
+		}
+	}
+
+	l := &ociLayout{dir: dir}
+	content, err := os.ReadFile(filepath.Join(dir, ociLayoutIndex))
+	if err != nil {
+		return nil, cleanup, fmt.Errorf("%w: %w", errImageLayout, err)
+	}
+	if err := json.Unmarshal(content, &l.index); err != nil {
+		return nil, cleanup, fmt.Errorf("%w: parsing %s: %w", errImageLayout, ociLayoutIndex, err)
+	}
+	return l, cleanup, nil
+}
+
+// contains returns true if the index of the layout refers to the digest.
+func (l *ociLayout) contains(digest Digest) bool {
+	for _, d := range l.index.Manifests {
+		if d.Digest == digest.Alg+":"+digest.Value {
+			return true
+		}
+	}
+	return false
+}
+
+// readBlob reads the blob with the digest, and verifies its content.
+func (l *ociLayout) readBlob(digest string) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := l.verifyBlob(digest, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// verifyBlob verifies that the content of the blob matches its digest. The
+// content is also copied to w, if it is not nil.
+func (l *ociLayout) verifyBlob(digest string, w io.Writer) error {
+	alg, value, ok := strings.Cut(digest, ":")
+	if !ok || strings.ContainsAny(value, `/\.`) {
+		return fmt.Errorf("%w: malformed digest %q", errImageLayout, digest)
+	}
+	f, err := os.Open(filepath.Join(l.dir, ociLayoutBlobs, alg, value))
+	if err != nil {
+		return fmt.Errorf("%w: %w", errImageLayout, err)
+	}
+	defer f.Close()
+
+	digests, err := utils.HashReader(w, f, alg)
+	if err != nil {
+		return fmt.Errorf("%w: reading blob %q: %w", errImageLayout, digest, err)
+	}
+	if got := alg + ":" + digests[alg]; got != digest {
+		return fmt.Errorf("%w: blob %q has digest %q", errImageDigest, digest, got)
+	}
+	return nil
+}
+
+// verify verifies the content of the image with the digest in the layout: its
+// index, if any, and the manifest, config and layers of the image for the
+// current platform. It returns the possible IDs of the image once loaded.
+func (l *ociLayout) verify(digest Digest) ([]string, error) {
+	ref := digest.Alg + ":" + digest.Value
+	content, err := l.readBlob(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{ref}
+	var index ociIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("%w: parsing %q: %w", errImageLayout, ref, err)
+	}
+	if len(index.Manifests) > 0 {
+		manifest, err := platformManifest(index)
+		if err != nil {
+			return nil, err
+		}
+		if content, err = l.readBlob(manifest.Digest); err != nil {
+			return nil, err
+		}
+		ids = append(ids, manifest.Digest)
+	}
+
+	var manifest ociManifest
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: parsing the manifest of %q: %w", errImageLayout, ref, err)
+	}
+	if manifest.Config == nil {
+		return nil, fmt.Errorf("%w: the manifest of %q has no config", errImageLayout, ref)
+	}
+	for _, d := range append([]ociDescriptor{*manifest.Config}, manifest.Layers...) {
+		if err := l.verifyBlob(d.Digest, nil); err != nil {
+			return nil, err
+		}
+	}
+	// The config digest is the ID of the image in the Docker image store.
+	return append([]string{manifest.Config.Digest}, ids...), nil
+}
+
+// platformManifest returns the descriptor of the manifest of the image index
+// for the current platform.
+func platformManifest(index ociIndex) (*ociDescriptor, error) {
+	for i, d := range index.Manifests {
+		if d.Platform != nil && d.Platform.OS == "linux" && d.Platform.Architecture == runtime.GOARCH {
+			return &index.Manifests[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no image for linux/%s in the index", errImageLayout, runtime.GOARCH)
+}
+
+// load loads the layout into the runtime, as a tarball.
+func (l *ociLayout) load(ctx context.Context, rt ContainerRuntime) error {
+	log.Printf("Loading the OCI image layout %q.", l.dir)
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, l.dir))
+	}()
+	err := rt.LoadImage(ctx, pr)
+	pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("%w: loading the layout: %w", errImageLayout, err)
+	}
+	return nil
+}
+
+// writeTar writes the regular files and directories under dir as a tarball.
+func writeTar(w io.Writer, dir string) error {
+	tw := tar.NewWriter(w)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || p == dir {
+			return err
+		}
+		if !d.IsDir() && !d.Type().IsRegular() {
+			return fmt.Errorf("%w: %q is not a regular file", errImageLayout, p)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if d.IsDir() {
+			hdr.Name += "/"
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,368 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const testImageName = "ghcr.io/slsa-framework/builder"
+
+// writeBlob writes the content as a blob of the OCI image layout in dir, and
+// returns its descriptor.
+func writeBlob(t *testing.T, dir string, content []byte) ociDescriptor {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	value := hex.EncodeToString(sum[:])
+	blobs := filepath.Join(dir, ociLayoutBlobs, "sha256")
+	if err := os.MkdirAll(blobs, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(blobs, value), content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return ociDescriptor{Digest: "sha256:" + value, Size: int64(len(content))}
+}
+
+// writeJSONBlob writes the value as a JSON blob of the OCI image layout in dir.
+func writeJSONBlob(t *testing.T, dir string, v any) ociDescriptor {
+	t.Helper()
+	content, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writeBlob(t, dir, content)
+}
+
+// testLayout is an OCI image layout with a single image.
+type testLayout struct {
+	dir string
+	// Descriptor of the image in the index of the layout.
+	image  ociDescriptor
+	config ociDescriptor
+	layer  ociDescriptor
+	// Descriptor of the manifest for the current platform, if the image is
+	// an image index.
+	manifest *ociDescriptor
+}
+
+// digest returns the digest of the image.
+func (l *testLayout) digest() Digest {
+	d, err := validateDigest(l.image.Digest)
+	if err != nil {
+		panic(err)
+	}
+	return *d
+}
+
+// writeLayout writes an OCI image layout with an image to dir. If multiArch
+// is true, the image is an index with manifests for several platforms.
+func writeLayout(t *testing.T, dir string, multiArch bool) *testLayout {
+	t.Helper()
+	l := &testLayout{dir: dir}
+	l.config = writeBlob(t, dir, []byte(`{"architecture":"`+runtime.GOARCH+`","os":"linux"}`))
+	l.layer = writeBlob(t, dir, []byte("layer"))
+	manifest := writeJSONBlob(t, dir, ociManifest{Config: &l.config, Layers: []ociDescriptor{l.layer}})
+	l.image = manifest
+	if multiArch {
+		other := writeJSONBlob(t, dir, ociManifest{Config: &ociDescriptor{Digest: "sha256:missing"}})
+		other.Platform = &ociPlatform{OS: "linux", Architecture: "other"}
+		manifest.Platform = &ociPlatform{OS: "linux", Architecture: runtime.GOARCH}
+		l.manifest = &manifest
+		l.image = writeJSONBlob(t, dir, ociIndex{Manifests: []ociDescriptor{other, manifest}})
+	}
+	if err := os.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	content, err := json.Marshal(ociIndex{Manifests: []ociDescriptor{l.image}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ociLayoutIndex), content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func Test_ociLayout_verify(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		multiArch bool
+		tamper    func(l *testLayout) string
+		expected  error
+	}{
+		{
+			name: "manifest",
+		},
+		{
+			name:      "index",
+			multiArch: true,
+		},
+		{
+			name:     "tampered layer",
+			tamper:   func(l *testLayout) string { return l.layer.Digest },
+			expected: errImageDigest,
+		},
+		{
+			name:     "tampered config",
+			tamper:   func(l *testLayout) string { return l.config.Digest },
+			expected: errImageDigest,
+		},
+		{
+			name:      "tampered platform manifest",
+			multiArch: true,
+			tamper:    func(l *testLayout) string { return l.manifest.Digest },
+			expected:  errImageDigest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tl := writeLayout(t, t.TempDir(), tt.multiArch)
+			if tt.tamper != nil {
+				d, err := validateDigest(tt.tamper(tl))
+				if err != nil {
+					t.Fatal(err)
+				}
+				p := filepath.Join(tl.dir, ociLayoutBlobs, d.Alg, d.Value)
+				if err := os.WriteFile(p, []byte("tampered"), 0o600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			l, cleanup, err := openOCILayout(tl.dir)
+			defer cleanup()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !l.contains(tl.digest()) {
+				t.Errorf("the layout does not contain %q", tl.image.Digest)
+			}
+
+			ids, err := l.verify(tl.digest())
+			if diff := cmp.Diff(tt.expected, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+			if err != nil {
+				return
+			}
+			want := []string{tl.config.Digest, tl.image.Digest}
+			if tl.manifest != nil {
+				want = append(want, tl.manifest.Digest)
+			}
+			if diff := cmp.Diff(want, ids); diff != "" {
+				t.Errorf("unexpected IDs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_openOCILayout_tarball(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	tl := writeLayout(t, filepath.Join(dir, "layout"), false)
+	tarball := filepath.Join(dir, "layout.tar")
+	f, err := os.Create(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTar(f, tl.dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	l, cleanup, err := openOCILayout(tarball)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := l.verify(tl.digest()); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	cleanup()
+	if _, err := os.Stat(l.dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("the extracted layout was not removed: %v", err)
+	}
+
+	if _, cleanup, err := openOCILayout(filepath.Join(dir, "missing.tar")); !errors.Is(err, errImageLayout) {
+		cleanup()
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errImageLayout, cmpopts.EquateErrors()))
+	}
+}
+
+func Test_imageResolver_runtime(t *testing.T) {
+	t.Parallel()
+
+	digest := "sha256:" + sha256Hex("image")
+	image := testImageName + "@" + digest
+	tests := []struct {
+		name     string
+		rt       *testRuntime
+		want     string
+		pulled   []string
+		expected error
+	}{
+		{
+			name: "local image",
+			rt: &testRuntime{images: map[string]*ImageInfo{
+				image: {ID: "sha256:id", RepoDigests: []string{image}},
+			}},
+			want: "sha256:id",
+		},
+		{
+			name: "pulled image",
+			rt: &testRuntime{
+				images: map[string]*ImageInfo{},
+				pullable: map[string]*ImageInfo{
+					image: {ID: "sha256:id", RepoDigests: []string{"mirror.local/builder@" + digest}},
+				},
+			},
+			want:   "sha256:id",
+			pulled: []string{image},
+		},
+		{
+			name:     "missing image",
+			rt:       &testRuntime{images: map[string]*ImageInfo{}},
+			expected: errImageDigest,
+		},
+		{
+			name: "mismatched digest",
+			rt: &testRuntime{images: map[string]*ImageInfo{
+				image: {ID: "sha256:id", RepoDigests: []string{testImageName + "@sha256:" + sha256Hex("other")}},
+			}},
+			expected: errImageDigest,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := newImageResolver(tt.rt, nil).resolve(context.Background(), image)
+			if diff := cmp.Diff(tt.expected, err, cmpopts.EquateErrors()); diff != "" {
+				t.Fatalf("unexpected error (-want +got):\n%s", diff)
+			}
+			if got != tt.want {
+				t.Errorf("unexpected ID: got %q, want %q", got, tt.want)
+			}
+			if diff := cmp.Diff(tt.pulled, tt.rt.pulled); diff != "" {
+				t.Errorf("unexpected pulls (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_imageResolver_layout(t *testing.T) {
+	t.Parallel()
+
+	tl := writeLayout(t, t.TempDir(), true)
+	l, cleanup, err := openOCILayout(tl.dir)
+	defer cleanup()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Images that are not in the layout are resolved by the runtime.
+	other := testImageName + "@sha256:" + sha256Hex("other")
+	rt := &testRuntime{
+		images: map[string]*ImageInfo{
+			other: {ID: "sha256:other", RepoDigests: []string{other}},
+		},
+		loadable: map[string]*ImageInfo{
+			tl.config.Digest: {ID: tl.config.Digest},
+		},
+	}
+	r := newImageResolver(rt, l)
+	image := testImageName + "@" + tl.image.Digest
+	for range 2 {
+		got, err := r.resolve(context.Background(), image)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != tl.config.Digest {
+			t.Errorf("unexpected ID: got %q, want %q", got, tl.config.Digest)
+		}
+	}
+	if got, err := r.resolve(context.Background(), other); err != nil || got != "sha256:other" {
+		t.Errorf("unexpected result: %q, %v", got, err)
+	}
+	if len(rt.loaded) != 1 {
+		t.Errorf("the layout was loaded %d times, want once", len(rt.loaded))
+	}
+	if len(rt.pulled) != 0 {
+		t.Errorf("unexpected pulls: %q", rt.pulled)
+	}
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func Test_runDockerRun_images(t *testing.T) {
+	const codegenImage = "codegen@sha256:1111"
+
+	rt := &testRuntime{images: map[string]*ImageInfo{
+		codegenImage:       {ID: "sha256:codegen", RepoDigests: []string{codegenImage}},
+		"bash@sha256:abcd": {ID: "sha256:bash", RepoDigests: []string{"docker.io/library/bash@sha256:abcd"}},
+	}}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		buildConfig: &BuildConfig{
+			Steps: []BuildStep{
+				{Command: []string{"codegen"}, BuilderImage: codegenImage},
+				{Command: []string{"make"}},
+			},
+		},
+		runtime: rt,
+	}
+	if err := runDockerRun(context.Background(), db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got []string
+	for _, c := range rt.commands {
+		got = append(got, c[3])
+	}
+	if diff := cmp.Diff([]string{"sha256:codegen", "sha256:bash"}, got); diff != "" {
+		t.Errorf("unexpected images (-want +got):\n%s", diff)
+	}
+
+	// No step is run if any of the images cannot be verified.
+	rt.commands = nil
+	rt.images[codegenImage] = &ImageInfo{ID: "sha256:codegen", RepoDigests: []string{"codegen@sha256:2222"}}
+	if err := runDockerRun(context.Background(), db); !errors.Is(err, errImageDigest) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errImageDigest, cmpopts.EquateErrors()))
+	}
+	if len(rt.commands) != 0 {
+		t.Errorf("unexpected commands: %q", rt.commands)
+	}
+}
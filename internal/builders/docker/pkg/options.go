@@ -14,16 +14,27 @@
 
 package pkg
 
-import "github.com/spf13/cobra"
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
 
 // InputOptions are the common options for the dry run and build command.
 type InputOptions struct {
-	BuildConfigPath string
-	SourceRepo      string
-	GitCommitHash   string
-	BuilderImage    string
-	ForceCheckout   bool
-	Verbose         bool
+	BuildConfigPath    string
+	SourceRepo         string
+	GitCommitHash      string
+	BuilderImage       string
+	ContainerRuntime   string
+	BuilderImageLayout string
+	Timeout            time.Duration
+	CPUs               string
+	Memory             string
+	PidsLimit          int64
+	Secrets            []string
+	ForceCheckout      bool
+	Verbose            bool
 }
 
 // AddFlags adds input flags to the given command.
@@ -32,13 +43,37 @@ func (io *InputOptions) AddFlags(cmd *cobra.Command) {
 		"Required - Path to a toml file containing the build configs.")
 
 	cmd.Flags().StringVarP(&io.SourceRepo, "source-repo", "s", "",
-		"Required - URL of the source repo.")
+		"Required - URL of the source repo, or a file URI of a local source tarball, Git bundle, or directory.")
 
 	cmd.Flags().StringVarP(&io.GitCommitHash, "git-commit-digest", "d", "",
-		"Required - SHA1 Git commit digest of the revision of the source code to build the artefact from.")
+		"Required - Digest of the source code to build the artefact from: the sha1 Git commit digest for a Git "+
+			"repo or bundle, the sha256 or sha512 digest of a tarball, or the gitTree digest of a directory.")
 
 	cmd.Flags().StringVarP(&io.BuilderImage, "builder-image", "i", "",
-		"Required - URL indicating the Docker builder image, including a URI and image digest.")
+		"Required unless the build config sets builder_dockerfile - URL indicating the Docker builder image, "+
+			"including a URI and image digest.")
+
+	cmd.Flags().StringVar(&io.ContainerRuntime, "container-runtime", DockerRuntime,
+		"Optional - Container runtime used to run the build: docker, podman or nerdctl.")
+
+	cmd.Flags().StringVar(&io.BuilderImageLayout, "builder-image-layout", "",
+		"Optional - Path to an OCI image layout directory or tarball to load the builder images from.")
+
+	cmd.Flags().DurationVar(&io.Timeout, "timeout", 0,
+		"Optional - Maximum wall-clock duration of the build, e.g., 1h30m. Overrides the timeout of the build config.")
+
+	cmd.Flags().StringVar(&io.CPUs, "cpus", "",
+		"Optional - Number of CPUs available to each build container. Overrides the cpus of the build config.")
+
+	cmd.Flags().StringVar(&io.Memory, "memory", "",
+		"Optional - Memory limit of each build container, e.g., 4g. Overrides the memory of the build config.")
+
+	cmd.Flags().Int64Var(&io.PidsLimit, "pids-limit", 0,
+		"Optional - Maximum number of processes in each build container. Overrides the pids_limit of the build config.")
+
+	cmd.Flags().StringArrayVar(&io.Secrets, "secret", nil,
+		"Optional - Secret required by the build config, as id=NAME,src=PATH or id=NAME,env=VARIABLE. "+
+			"Can be repeated.")
 
 	cmd.Flags().BoolVarP(&io.ForceCheckout, "force-checkout", "f", false,
 		"Optional - Forces checking out the source code from the given Git repo.")
@@ -0,0 +1,144 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the checks of the output folder of the `build`
+// subcommand, and the sha256sum manifest of the subjects it writes there.
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+// errOutputFolder indicates an output folder that is not allowed by the
+// OutputFolderPolicy.
+var errOutputFolder = errors.New("output folder")
+
+// DefaultOutputFolderRoots are the directories that the output folder of the
+// `build` subcommand must be in by default.
+var DefaultOutputFolderRoots = []string{"/tmp"}
+
+// OutputFolderPolicy restricts the folders that the artifacts can be written
+// to. An output folder must be strictly inside one of the roots once all the
+// symlinks are resolved, and must either not exist or be an empty directory.
+type OutputFolderPolicy struct {
+	// Roots are the absolute paths of the directories that the output folder
+	// must be in.
+	Roots []string
+}
+
+// Check checks that the output folder is allowed by the policy, and returns
+// its absolute path with the symlinks resolved, which is used to write the
+// artifacts.
+func (p *OutputFolderPolicy) Check(folder string) (string, error) {
+	abs, err := filepath.Abs(folder)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errOutputFolder, err)
+	}
+	resolved, err := resolveSymlinks(abs)
+	if err != nil {
+		return "", fmt.Errorf("%w: resolving %q: %v", errOutputFolder, abs, err)
+	}
+
+	allowed := false
+	for _, root := range p.Roots {
+		if !filepath.IsAbs(root) {
+			return "", fmt.Errorf("%w: root %q must be an absolute path", errOutputFolder, root)
+		}
+		r, err := resolveSymlinks(filepath.Clean(root))
+		if err != nil {
+			return "", fmt.Errorf("%w: resolving root %q: %v", errOutputFolder, root, err)
+		}
+		// The root itself is not allowed, so that the artifacts cannot be
+		// mixed with other files.
+		if rel, err := filepath.Rel(r, resolved); err == nil && rel != "." && filepath.IsLocal(rel) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return "", fmt.Errorf("%w: %q must be in one of %q", errOutputFolder, resolved, p.Roots)
+	}
+
+	entries, err := os.ReadDir(resolved)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return "", fmt.Errorf("%w: %v", errOutputFolder, err)
+	case len(entries) > 0:
+		return "", fmt.Errorf("%w: %q must be empty", errOutputFolder, resolved)
+	}
+	return resolved, nil
+}
+
+// resolveSymlinks returns the absolute path p with the symlinks resolved. The
+// path does not need to exist, in which case the symlinks of its longest
+// existing prefix are resolved.
+func resolveSymlinks(p string) (string, error) {
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return filepath.Join(append([]string{resolved}, missing...)...), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		// A dangling symlink could be created later, outside of the roots.
+		if _, err := os.Lstat(p); err == nil {
+			return "", fmt.Errorf("%q is a dangling symlink", p)
+		}
+		parent := filepath.Dir(p)
+		if parent == p {
+			return "", err
+		}
+		missing = append([]string{filepath.Base(p)}, missing...)
+		p = parent
+	}
+}
+
+// WriteSHA256Sum writes the names and SHA256 digests of the subjects in the
+// format of sha256sum. If encode is true, the manifest is base64 encoded, as
+// expected by the `--subjects-filename` flag and the `base64-subjects` input
+// of the generic builder.
+func WriteSHA256Sum(w io.Writer, subjects []intoto.Subject, encode bool) error {
+	var b strings.Builder
+	for _, s := range subjects {
+		digest, ok := s.Digest["sha256"]
+		if !ok {
+			return fmt.Errorf("subject %q has no sha256 digest", s.Name)
+		}
+		if strings.ContainsAny(s.Name, "\r\n") {
+			return fmt.Errorf("subject name %q contains a newline", s.Name)
+		}
+		fmt.Fprintf(&b, "%s  %s\n", digest, s.Name)
+	}
+	content := b.String()
+	if encode {
+		content = base64.StdEncoding.EncodeToString([]byte(content))
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		return fmt.Errorf("writing the sha256sum manifest: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+func Test_OutputFolderPolicy_Check(t *testing.T) {
+	t.Parallel()
+
+	// The temporary directory may itself be behind a symlink.
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "tmp")
+	writeFiles(t, root, "full/file")
+	writeFiles(t, dir, "tmpfoo/.keep")
+	for _, d := range []string{"empty", "link-target"} {
+		if err := os.MkdirAll(filepath.Join(root, d), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	links := map[string]string{
+		"inside":   filepath.Join(root, "link-target"),
+		"outside":  filepath.Join(dir, "tmpfoo"),
+		"dangling": filepath.Join(dir, "missing"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		folder string
+		roots  []string
+		want   string
+		err    error
+	}{
+		{
+			name:   "new folder",
+			folder: filepath.Join(root, "out"),
+			want:   filepath.Join(root, "out"),
+		},
+		{
+			name:   "new nested folder",
+			folder: filepath.Join(root, "out", "linux"),
+			want:   filepath.Join(root, "out", "linux"),
+		},
+		{
+			name:   "empty folder",
+			folder: filepath.Join(root, "empty"),
+			want:   filepath.Join(root, "empty"),
+		},
+		{
+			name:   "symlink inside the root",
+			folder: filepath.Join(root, "inside", "out"),
+			want:   filepath.Join(root, "link-target", "out"),
+		},
+		{
+			name:   "second root",
+			folder: filepath.Join(dir, "tmpfoo", "out"),
+			roots:  []string{root, filepath.Join(dir, "tmpfoo")},
+			want:   filepath.Join(dir, "tmpfoo", "out"),
+		},
+		{
+			name:   "non-empty folder",
+			folder: filepath.Join(root, "full"),
+			err:    errOutputFolder,
+		},
+		{
+			name:   "root",
+			folder: root,
+			err:    errOutputFolder,
+		},
+		{
+			name:   "sibling with the root as prefix",
+			folder: filepath.Join(dir, "tmpfoo", "out"),
+			err:    errOutputFolder,
+		},
+		{
+			name:   "parent traversal",
+			folder: filepath.Join(root, "..", "out"),
+			err:    errOutputFolder,
+		},
+		{
+			name:   "symlink outside the root",
+			folder: filepath.Join(root, "outside", "out"),
+			err:    errOutputFolder,
+		},
+		{
+			name:   "dangling symlink",
+			folder: filepath.Join(root, "dangling"),
+			err:    errOutputFolder,
+		},
+		{
+			name:   "relative root",
+			folder: filepath.Join(root, "out"),
+			roots:  []string{"tmp"},
+			err:    errOutputFolder,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			roots := tt.roots
+			if roots == nil {
+				roots = []string{root}
+			}
+			got, err := (&OutputFolderPolicy{Roots: roots}).Check(tt.folder)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.err, cmpopts.EquateErrors()))
+			}
+			if got != tt.want {
+				t.Errorf("unexpected output folder, got: %q, want: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_WriteSHA256Sum(t *testing.T) {
+	t.Parallel()
+
+	subjects := []intoto.Subject{
+		{Name: "app", Digest: map[string]string{"sha256": "aaaa"}},
+		{Name: "out/linux/app", Digest: map[string]string{"sha256": "bbbb", "sha512": "cccc"}},
+	}
+	want := "aaaa  app\nbbbb  out/linux/app\n"
+
+	var b strings.Builder
+	if err := WriteSHA256Sum(&b, subjects, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Errorf("unexpected manifest (-want +got):\n%s", diff)
+	}
+
+	b.Reset()
+	if err := WriteSHA256Sum(&b, subjects, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	decoded, err := base64.StdEncoding.DecodeString(b.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(want, string(decoded)); diff != "" {
+		t.Errorf("unexpected decoded manifest (-want +got):\n%s", diff)
+	}
+
+	for _, s := range []intoto.Subject{
+		{Name: "app", Digest: map[string]string{"sha512": "cccc"}},
+		{Name: "app\nbbbb  other", Digest: map[string]string{"sha256": "aaaa"}},
+	} {
+		if err := WriteSHA256Sum(&b, []intoto.Subject{s}, false); err == nil {
+			t.Errorf("expected an error for subject %q", s.Name)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the generation of the SLSA v1.0 provenance from the
+// outputs of the `dry-run` and `build` subcommands.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/utils"
+	"github.com/slsa-framework/slsa-github-generator/signing"
+)
+
+// errProvenance indicates that the provenance could not be generated.
+var errProvenance = errors.New("provenance")
+
+// BuildRunMetadata describes a run of the `build` subcommand. It is recorded
+// in the RunDetails of the provenance.
+type BuildRunMetadata struct {
+	// The timestamp of when the build started.
+	StartedOn *time.Time `json:"startedOn,omitempty"`
+
+	// The timestamp of when the build completed.
+	FinishedOn *time.Time `json:"finishedOn,omitempty"`
+
+	// Additional artifacts generated during the build, such as logs.
+	Byproducts []slsa1.ResourceDescriptor `json:"byproducts,omitempty"`
+
+	// Mapping from the names of the subjects to their paths relative to the
+	// root of the source repository.
+	SubjectPaths map[string]string `json:"subjectPaths,omitempty"`
+
+	// Dependencies resolved during the build, such as the builder image built
+	// from the builder Dockerfile.
+	ResolvedDependencies []slsa1.ResourceDescriptor `json:"resolvedDependencies,omitempty"`
+}
+
+// RunDetailsOptions contains the details of a build run that are not known to
+// the `build` subcommand.
+type RunDetailsOptions struct {
+	// ID of the builder, e.g., the URI of the reusable workflow at a ref.
+	BuilderID string
+
+	// ID of the build invocation, e.g., the URL of the GitHub Actions run.
+	InvocationID string
+}
+
+// NewRunDetails returns the RunDetails of a build run. The metadata can be
+// nil if it was not recorded.
+func NewRunDetails(opts RunDetailsOptions, metadata *BuildRunMetadata) (*slsa1.ProvenanceRunDetails, error) {
+	if opts.BuilderID == "" {
+		return nil, fmt.Errorf("%w: builder ID must be set", errProvenance)
+	}
+	rd := &slsa1.ProvenanceRunDetails{
+		Builder: slsa1.Builder{ID: opts.BuilderID},
+		BuildMetadata: slsa1.BuildMetadata{
+			InvocationID: opts.InvocationID,
+		},
+	}
+	if metadata != nil {
+		rd.BuildMetadata.StartedOn = metadata.StartedOn
+		rd.BuildMetadata.FinishedOn = metadata.FinishedOn
+		rd.Byproducts = metadata.Byproducts
+	}
+	return rd, nil
+}
+
+// NewProvenanceStatement returns an in-toto statement with a SLSA v1.0
+// provenance predicate for the subjects.
+func NewProvenanceStatement(subjects []intoto.Subject, bd *slsa1.ProvenanceBuildDefinition,
+	rd *slsa1.ProvenanceRunDetails,
+) (*intoto.Statement, error) {
+	if len(subjects) == 0 {
+		return nil, fmt.Errorf("%w: no subjects", errProvenance)
+	}
+	return &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: slsa1.PredicateSLSAProvenance,
+			Subject:       subjects,
+		},
+		Predicate: slsa1.ProvenancePredicate{
+			BuildDefinition: *bd,
+			RunDetails:      *rd,
+		},
+	}, nil
+}
+
+// RecordSubjectPaths records the mapping from the names of the subjects to
+// their paths in the InternalParameters of the BuildDefinition.
+func RecordSubjectPaths(bd *slsa1.ProvenanceBuildDefinition, paths map[string]string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+	switch ip := bd.InternalParameters.(type) {
+	case nil:
+		bd.InternalParameters = map[string]any{SubjectPathsKey: paths}
+	case map[string]any:
+		ip[SubjectPathsKey] = paths
+	default:
+		return fmt.Errorf("%w: unexpected internal parameters type %T", errProvenance, ip)
+	}
+	return nil
+}
+
+// RecordResolvedDependencies appends the dependencies resolved during the
+// build to the ResolvedDependencies of the BuildDefinition.
+func RecordResolvedDependencies(bd *slsa1.ProvenanceBuildDefinition, deps []slsa1.ResourceDescriptor) {
+	bd.ResolvedDependencies = append(bd.ResolvedDependencies, deps...)
+}
+
+// SignProvenance signs the provenance statement using the signer, and returns
+// the signed attestation.
+func SignProvenance(ctx context.Context, signer signing.Signer, statement *intoto.Statement) ([]byte, error) {
+	att, err := signer.Sign(ctx, statement)
+	if err != nil {
+		return nil, fmt.Errorf("%w: signing the statement: %w", errProvenance, err)
+	}
+	return att.Bytes(), nil
+}
+
+// FileByproduct returns a ResourceDescriptor with the name and the SHA256
+// digest of the file, for recording it as a byproduct of the build. The path
+// must be under the current directory.
+func FileByproduct(path string) (*slsa1.ResourceDescriptor, error) {
+	digests, err := utils.HashFiles([]string{path}, utils.HashOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("%w: hashing byproduct %q: %w", errProvenance, path, err)
+	}
+	return &slsa1.ResourceDescriptor{
+		Name:   filepath.Base(path),
+		Digest: digests[0],
+	}, nil
+}
@@ -0,0 +1,189 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/testutil"
+)
+
+func Test_NewRunDetails(t *testing.T) {
+	t.Parallel()
+
+	startedOn := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	finishedOn := startedOn.Add(time.Minute)
+	log := slsa1.ResourceDescriptor{Name: "build.log", Digest: map[string]string{"sha256": "abcd"}}
+
+	tests := []struct {
+		expected error
+		name     string
+		opts     RunDetailsOptions
+		metadata *BuildRunMetadata
+		want     *slsa1.ProvenanceRunDetails
+	}{
+		{
+			name: "without metadata",
+			opts: RunDetailsOptions{BuilderID: "https://example.com/builder", InvocationID: "run-1"},
+			want: &slsa1.ProvenanceRunDetails{
+				Builder:       slsa1.Builder{ID: "https://example.com/builder"},
+				BuildMetadata: slsa1.BuildMetadata{InvocationID: "run-1"},
+			},
+		},
+		{
+			name: "with metadata",
+			opts: RunDetailsOptions{BuilderID: "https://example.com/builder"},
+			metadata: &BuildRunMetadata{
+				StartedOn:  &startedOn,
+				FinishedOn: &finishedOn,
+				Byproducts: []slsa1.ResourceDescriptor{log},
+			},
+			want: &slsa1.ProvenanceRunDetails{
+				Builder: slsa1.Builder{ID: "https://example.com/builder"},
+				BuildMetadata: slsa1.BuildMetadata{
+					StartedOn:  &startedOn,
+					FinishedOn: &finishedOn,
+				},
+				Byproducts: []slsa1.ResourceDescriptor{log},
+			},
+		},
+		{
+			name:     "missing builder ID",
+			expected: errProvenance,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := NewRunDetails(tt.opts, tt.metadata)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected run details (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_NewProvenanceStatement(t *testing.T) {
+	t.Parallel()
+
+	subjects := []intoto.Subject{{Name: "app", Digest: map[string]string{"sha256": "abcd"}}}
+	bd := &slsa1.ProvenanceBuildDefinition{BuildType: ContainerBasedBuildType}
+	rd := &slsa1.ProvenanceRunDetails{Builder: slsa1.Builder{ID: "https://example.com/builder"}}
+
+	got, err := NewProvenanceStatement(subjects, bd, rd)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &intoto.Statement{
+		StatementHeader: intoto.StatementHeader{
+			Type:          intoto.StatementInTotoV01,
+			PredicateType: slsa1.PredicateSLSAProvenance,
+			Subject:       subjects,
+		},
+		Predicate: slsa1.ProvenancePredicate{BuildDefinition: *bd, RunDetails: *rd},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected statement (-want +got):\n%s", diff)
+	}
+
+	_, err = NewProvenanceStatement(nil, bd, rd)
+	if !errors.Is(err, errProvenance) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errProvenance, cmpopts.EquateErrors()))
+	}
+}
+
+func Test_SignProvenance(t *testing.T) {
+	t.Parallel()
+
+	signer := &testutil.TestSigner{Att: testutil.TestAttestation{BytesVal: []byte("signed")}}
+	got, err := SignProvenance(context.Background(), signer, &intoto.Statement{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(got) != "signed" {
+		t.Errorf("unexpected attestation, got: %q, want: %q", got, "signed")
+	}
+}
+
+func Test_FileByproduct(t *testing.T) {
+	f, err := os.CreateTemp(".", "build-*.log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("hello\n"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f.Close()
+
+	got, err := FileByproduct(f.Name())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := &slsa1.ResourceDescriptor{
+		Name:   f.Name()[2:],
+		Digest: map[string]string{"sha256": "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected byproduct (-want +got):\n%s", diff)
+	}
+
+	_, err = FileByproduct("../outside.log")
+	if !errors.Is(err, errProvenance) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errProvenance, cmpopts.EquateErrors()))
+	}
+}
+
+func Test_RecordSubjectPaths(t *testing.T) {
+	t.Parallel()
+
+	paths := map[string]string{"app": "out/linux/app"}
+
+	bd := &slsa1.ProvenanceBuildDefinition{}
+	if err := RecordSubjectPaths(bd, paths); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(map[string]any{SubjectPathsKey: paths}, bd.InternalParameters); diff != "" {
+		t.Errorf("unexpected internal parameters (-want +got):\n%s", diff)
+	}
+
+	bd = &slsa1.ProvenanceBuildDefinition{InternalParameters: map[string]any{HermeticKey: true}}
+	if err := RecordSubjectPaths(bd, paths); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]any{HermeticKey: true, SubjectPathsKey: paths}
+	if diff := cmp.Diff(want, bd.InternalParameters); diff != "" {
+		t.Errorf("unexpected internal parameters (-want +got):\n%s", diff)
+	}
+
+	bd = &slsa1.ProvenanceBuildDefinition{InternalParameters: "invalid"}
+	err := RecordSubjectPaths(bd, paths)
+	if !errors.Is(err, errProvenance) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errProvenance, cmpopts.EquateErrors()))
+	}
+}
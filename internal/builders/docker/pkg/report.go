@@ -0,0 +1,274 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the report generated when verifying a provenance by
+// rebuilding its subjects.
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+const (
+	// JSONReportFormat formats the VerificationReport as JSON.
+	JSONReportFormat = "json"
+
+	// MarkdownReportFormat formats the VerificationReport as markdown.
+	MarkdownReportFormat = "markdown"
+)
+
+var (
+	// errReportFormat indicates an unsupported report format.
+	errReportFormat = errors.New("report format")
+
+	// errExternalParameters indicates that the external parameters of a
+	// rebuild do not match the ones recorded in the provenance.
+	errExternalParameters = errors.New("external parameters mismatch")
+)
+
+// VerificationReport is the result of rebuilding the subjects of a provenance.
+type VerificationReport struct {
+	// URI of the source repository used for the rebuild.
+	SourceRepo string `json:"sourceRepo"`
+
+	// Commit of the source repository used for the rebuild, or the digest of
+	// the sources if they are not pinned by commit.
+	SourceCommit string `json:"sourceCommit"`
+
+	// Builder image used for the rebuild, pinned by digest.
+	BuilderImage string `json:"builderImage"`
+
+	// Whether all the subjects of the provenance were rebuilt with the same
+	// digests, and no other artifacts were built.
+	Reproducible bool `json:"reproducible"`
+
+	// Subjects that are both in the provenance and in the rebuild, sorted by
+	// name.
+	Subjects []SubjectComparison `json:"subjects"`
+
+	// Names of the subjects of the provenance that were not rebuilt.
+	Missing []string `json:"missing,omitempty"`
+
+	// Names of the rebuilt artifacts that are not subjects of the provenance.
+	Extra []string `json:"extra,omitempty"`
+}
+
+// SubjectComparison compares the digests of a subject in the provenance to
+// the digests of the rebuilt artifact.
+type SubjectComparison struct {
+	// Name of the subject.
+	Name string `json:"name"`
+
+	// Digests recorded in the provenance.
+	Expected map[string]string `json:"expected"`
+
+	// Digests of the rebuilt artifact.
+	Actual map[string]string `json:"actual"`
+
+	// Whether the digests match.
+	Match bool `json:"match"`
+}
+
+// NewVerificationReport compares the subjects of the provenance to the
+// rebuilt artifacts.
+func NewVerificationReport(provenance *ProvenanceStatementSLSA1, artifacts []intoto.Subject) *VerificationReport {
+	report := &VerificationReport{}
+	if ep, ok := provenance.Predicate.BuildDefinition.ExternalParameters.(ContainerBasedExternalParameters); ok {
+		report.SourceRepo = ep.Source.URI
+		if sd, err := sourceDigest(ep.Source.Digest); err == nil {
+			report.SourceCommit = sd.Value
+		}
+		report.BuilderImage = ep.BuilderImage.URI
+	}
+	report.compare(provenance.Subject, artifacts)
+	return report
+}
+
+// compare compares the expected subjects to the rebuilt artifacts, and sets
+// the result of the comparison in the report.
+func (r *VerificationReport) compare(subjects, artifacts []intoto.Subject) {
+	r.Reproducible = true
+	r.Subjects = []SubjectComparison{}
+
+	actual := make(map[string]intoto.Subject, len(artifacts))
+	for _, a := range artifacts {
+		actual[a.Name] = a
+	}
+
+	expected := make(map[string]bool, len(subjects))
+	for _, s := range subjects {
+		expected[s.Name] = true
+		a, ok := actual[s.Name]
+		if !ok {
+			r.Missing = append(r.Missing, s.Name)
+			r.Reproducible = false
+			continue
+		}
+		c := SubjectComparison{
+			Name:     s.Name,
+			Expected: s.Digest,
+			Actual:   a.Digest,
+			Match:    digestsMatch(s.Digest, a.Digest),
+		}
+		r.Subjects = append(r.Subjects, c)
+		r.Reproducible = r.Reproducible && c.Match
+	}
+	for _, a := range artifacts {
+		if !expected[a.Name] {
+			r.Extra = append(r.Extra, a.Name)
+			r.Reproducible = false
+		}
+	}
+
+	sort.Slice(r.Subjects, func(i, j int) bool {
+		return r.Subjects[i].Name < r.Subjects[j].Name
+	})
+	sort.Strings(r.Missing)
+	sort.Strings(r.Extra)
+}
+
+// NonReproducible returns the sorted names of the artifacts whose digests do
+// not match, or that were built only once.
+func (r *VerificationReport) NonReproducible() []string {
+	var names []string
+	for _, s := range r.Subjects {
+		if !s.Match {
+			names = append(names, s.Name)
+		}
+	}
+	names = append(append(names, r.Missing...), r.Extra...)
+	sort.Strings(names)
+	return names
+}
+
+// digestsMatch returns true if the digests share at least one algorithm, and
+// all the shared algorithms have the same value.
+func digestsMatch(expected, actual map[string]string) bool {
+	shared := 0
+	for alg, v := range expected {
+		a, ok := actual[alg]
+		if !ok {
+			continue
+		}
+		if a != v {
+			return false
+		}
+		shared++
+	}
+	return shared > 0
+}
+
+// Write writes the report to w in the given format.
+func (r *VerificationReport) Write(w io.Writer, format string) error {
+	switch format {
+	case JSONReportFormat:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			return fmt.Errorf("writing the report: %w", err)
+		}
+		return nil
+	case MarkdownReportFormat:
+		if _, err := io.WriteString(w, r.markdown()); err != nil {
+			return fmt.Errorf("writing the report: %w", err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported format %q, want %q or %q",
+			errReportFormat, format, JSONReportFormat, MarkdownReportFormat)
+	}
+}
+
+// markdown returns the report formatted as markdown.
+func (r *VerificationReport) markdown() string {
+	var sb strings.Builder
+	sb.WriteString("# Reproducibility report\n\n")
+	result := "reproducible"
+	if !r.Reproducible {
+		result = "not reproducible"
+	}
+	fmt.Fprintf(&sb, "- Result: **%s**\n", result)
+	fmt.Fprintf(&sb, "- Source: `%s`\n", r.SourceRepo)
+	fmt.Fprintf(&sb, "- Commit: `%s`\n", r.SourceCommit)
+	fmt.Fprintf(&sb, "- Builder image: `%s`\n", r.BuilderImage)
+
+	sb.WriteString("\n## Subjects\n\n")
+	sb.WriteString("| Name | Expected | Actual | Match |\n")
+	sb.WriteString("| --- | --- | --- | --- |\n")
+	for _, s := range r.Subjects {
+		match := "yes"
+		if !s.Match {
+			match = "no"
+		}
+		fmt.Fprintf(&sb, "| `%s` | %s | %s | %s |\n", s.Name, formatDigests(s.Expected), formatDigests(s.Actual), match)
+	}
+
+	for _, section := range []struct {
+		title string
+		names []string
+	}{
+		{"Missing artifacts", r.Missing},
+		{"Extra artifacts", r.Extra},
+	} {
+		if len(section.names) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n## %s\n\n", section.title)
+		for _, name := range section.names {
+			fmt.Fprintf(&sb, "- `%s`\n", name)
+		}
+	}
+	return sb.String()
+}
+
+// formatDigests formats the digests as `alg:value`, sorted by algorithm.
+func formatDigests(digests map[string]string) string {
+	algs := make([]string, 0, len(digests))
+	for alg := range digests {
+		algs = append(algs, alg)
+	}
+	sort.Strings(algs)
+	formatted := make([]string, 0, len(algs))
+	for _, alg := range algs {
+		formatted = append(formatted, fmt.Sprintf("`%s:%s`", alg, digests[alg]))
+	}
+	return strings.Join(formatted, "<br>")
+}
+
+// CheckExternalParameters checks that the external parameters of a rebuild
+// match the ones recorded in the provenance. The parameters are compared
+// using their JSON encoding, which omits empty optional fields.
+func (p *ProvenanceStatementSLSA1) CheckExternalParameters(got any) error {
+	wantBytes, err := json.Marshal(p.Predicate.BuildDefinition.ExternalParameters)
+	if err != nil {
+		return fmt.Errorf("marshaling the recorded external parameters: %w", err)
+	}
+	gotBytes, err := json.Marshal(got)
+	if err != nil {
+		return fmt.Errorf("marshaling the external parameters: %w", err)
+	}
+	if !bytes.Equal(wantBytes, gotBytes) {
+		return fmt.Errorf("%w: recorded %s, got %s", errExternalParameters, wantBytes, gotBytes)
+	}
+	return nil
+}
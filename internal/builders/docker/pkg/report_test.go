@@ -0,0 +1,248 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+func testReportProvenance(subjects ...intoto.Subject) *ProvenanceStatementSLSA1 {
+	p := &ProvenanceStatementSLSA1{}
+	p.Subject = subjects
+	p.Predicate.BuildDefinition.ExternalParameters = ContainerBasedExternalParameters{
+		Source: slsa1.ResourceDescriptor{
+			URI:    "git+https://github.com/slsa-framework/slsa-github-generator@refs/heads/main",
+			Digest: map[string]string{"sha1": "cf5804b5c6f1a4b2a0b03401a487dfdfbe3a5f00"},
+		},
+		BuilderImage: slsa1.ResourceDescriptor{
+			URI:    "bash@sha256:abcd",
+			Digest: map[string]string{"sha256": "abcd"},
+		},
+		Config: BuildConfig{Command: []string{"make"}},
+	}
+	return p
+}
+
+func subject(name, digest string) intoto.Subject {
+	return intoto.Subject{Name: name, Digest: map[string]string{"sha256": digest}}
+}
+
+func Test_NewVerificationReport(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		expected  []intoto.Subject
+		artifacts []intoto.Subject
+		want      *VerificationReport
+	}{
+		{
+			name:      "reproducible",
+			expected:  []intoto.Subject{subject("b", "2222"), subject("a", "1111")},
+			artifacts: []intoto.Subject{subject("a", "1111"), subject("b", "2222")},
+			want: &VerificationReport{
+				Reproducible: true,
+				Subjects: []SubjectComparison{
+					{Name: "a", Expected: map[string]string{"sha256": "1111"}, Actual: map[string]string{"sha256": "1111"}, Match: true},
+					{Name: "b", Expected: map[string]string{"sha256": "2222"}, Actual: map[string]string{"sha256": "2222"}, Match: true},
+				},
+			},
+		},
+		{
+			name:      "digest mismatch",
+			expected:  []intoto.Subject{subject("a", "1111")},
+			artifacts: []intoto.Subject{subject("a", "9999")},
+			want: &VerificationReport{
+				Subjects: []SubjectComparison{
+					{Name: "a", Expected: map[string]string{"sha256": "1111"}, Actual: map[string]string{"sha256": "9999"}},
+				},
+			},
+		},
+		{
+			name:      "no shared algorithm",
+			expected:  []intoto.Subject{{Name: "a", Digest: map[string]string{"sha512": "1111"}}},
+			artifacts: []intoto.Subject{subject("a", "1111")},
+			want: &VerificationReport{
+				Subjects: []SubjectComparison{
+					{Name: "a", Expected: map[string]string{"sha512": "1111"}, Actual: map[string]string{"sha256": "1111"}},
+				},
+			},
+		},
+		{
+			name:      "missing and extra",
+			expected:  []intoto.Subject{subject("a", "1111"), subject("b", "2222")},
+			artifacts: []intoto.Subject{subject("a", "1111"), subject("c", "3333")},
+			want: &VerificationReport{
+				Subjects: []SubjectComparison{
+					{Name: "a", Expected: map[string]string{"sha256": "1111"}, Actual: map[string]string{"sha256": "1111"}, Match: true},
+				},
+				Missing: []string{"b"},
+				Extra:   []string{"c"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := NewVerificationReport(testReportProvenance(tt.expected...), tt.artifacts)
+			tt.want.SourceRepo = "git+https://github.com/slsa-framework/slsa-github-generator@refs/heads/main"
+			tt.want.SourceCommit = "cf5804b5c6f1a4b2a0b03401a487dfdfbe3a5f00"
+			tt.want.BuilderImage = "bash@sha256:abcd"
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected report (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_NewVerificationReport_sourceDigest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		digest map[string]string
+		want   string
+	}{
+		{
+			name:   "commit",
+			digest: map[string]string{"sha1": "cf58"},
+			want:   "cf58",
+		},
+		{
+			name:   "tree",
+			digest: map[string]string{gitTreeAlg: "4b82"},
+			want:   "4b82",
+		},
+		{
+			name:   "tarball",
+			digest: map[string]string{"sha256": "abcd", "sha512": "ef01"},
+			want:   "abcd",
+		},
+		{
+			name:   "sha512",
+			digest: map[string]string{"sha512": "ef01"},
+			want:   "ef01",
+		},
+		{
+			name:   "unsupported",
+			digest: map[string]string{"md5": "1234"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p := testReportProvenance()
+			ep := p.Predicate.BuildDefinition.ExternalParameters.(ContainerBasedExternalParameters)
+			ep.Source.Digest = tt.digest
+			p.Predicate.BuildDefinition.ExternalParameters = ep
+			if got := NewVerificationReport(p, nil).SourceCommit; got != tt.want {
+				t.Errorf("unexpected source commit, got: %q, want: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_VerificationReport_NonReproducible(t *testing.T) {
+	t.Parallel()
+
+	report := &VerificationReport{}
+	report.compare(
+		[]intoto.Subject{subject("d", "4444"), subject("a", "1111"), subject("b", "2222")},
+		[]intoto.Subject{subject("a", "1111"), subject("c", "3333"), subject("d", "0000")},
+	)
+	if report.Reproducible {
+		t.Error("unexpected reproducible report")
+	}
+	if diff := cmp.Diff([]string{"b", "c", "d"}, report.NonReproducible()); diff != "" {
+		t.Errorf("unexpected names (-want +got):\n%s", diff)
+	}
+}
+
+func Test_VerificationReport_Write(t *testing.T) {
+	t.Parallel()
+
+	report := NewVerificationReport(
+		testReportProvenance(subject("a", "1111"), subject("b", "2222")),
+		[]intoto.Subject{subject("a", "9999")},
+	)
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, JSONReportFormat); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got VerificationReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(report, &got); diff != "" {
+		t.Errorf("unexpected JSON report (-want +got):\n%s", diff)
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, MarkdownReportFormat); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"- Result: **not reproducible**",
+		"- Commit: `cf5804b5c6f1a4b2a0b03401a487dfdfbe3a5f00`",
+		"- Builder image: `bash@sha256:abcd`",
+		"| `a` | `sha256:1111` | `sha256:9999` | no |",
+		"## Missing artifacts\n\n- `b`\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("markdown report does not contain %q:\n%s", want, buf.String())
+		}
+	}
+
+	err := report.Write(&buf, "html")
+	if !errors.Is(err, errReportFormat) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errReportFormat, cmpopts.EquateErrors()))
+	}
+}
+
+func Test_CheckExternalParameters(t *testing.T) {
+	t.Parallel()
+
+	p := testReportProvenance()
+	ep, ok := p.Predicate.BuildDefinition.ExternalParameters.(ContainerBasedExternalParameters)
+	if !ok {
+		t.Fatalf("unexpected external parameters type")
+	}
+	if err := p.CheckExternalParameters(ep); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	ep.Config.Env = map[string]string{}
+	if err := p.CheckExternalParameters(ep); err != nil {
+		t.Errorf("unexpected error for empty env: %v", err)
+	}
+
+	ep.Config.Command = []string{"make", "all"}
+	err := p.CheckExternalParameters(ep)
+	if !errors.Is(err, errExternalParameters) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errExternalParameters, cmpopts.EquateErrors()))
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the reproducibility check of the `build` subcommand,
+// which builds the artifacts a second time and compares their digests.
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+// rebuildWorkspaceDir is the directory in the container where the source
+// repository is mounted for the second build of a reproducibility check, so
+// that artifacts depending on the path of the workspace are detected.
+const rebuildWorkspaceDir = "/rebuild/workspace"
+
+// sourceDateEpochEnv is the environment variable that build tools use instead
+// of the current time for the timestamps embedded in their outputs. See
+// https://reproducible-builds.org/specs/source-date-epoch/.
+const sourceDateEpochEnv = "SOURCE_DATE_EPOCH"
+
+// CheckReproducibility builds the artifacts of the build a second time, and
+// returns a report comparing the given artifacts of the first build to the
+// rebuilt artifacts. The second build uses a fresh checkout of the sources,
+// mounted at a different path in the container, and with SOURCE_DATE_EPOCH
+// set to a time in the future, unless the build config sets it. Every field of
+// that date differs from the current date, so timestamps embedded in the
+// artifacts are detected. The rebuilt artifacts are not kept.
+func (db *DockerBuild) CheckReproducibility(ctx context.Context, artifacts []intoto.Subject) (*VerificationReport, error) {
+	// Fetching the sources changes the working directory, which is restored
+	// once the second checkout is removed.
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get the current working directory: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(wd); err != nil {
+			log.Printf("couldn't restore the working directory %q: %v", wd, err)
+		}
+	}()
+
+	config := *db.config
+	config.ForceCheckout = true
+	f, err := newFetcher(&config)
+	if err != nil {
+		return nil, fmt.Errorf("creating the fetcher of the rebuild: %w", err)
+	}
+	b := &Builder{repoFetcher: f, runtime: db.runtime, config: config}
+	rebuild, err := b.SetUpBuildState(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("setting up the rebuild: %w", err)
+	}
+	defer rebuild.RepoInfo.Cleanup()
+	rebuild.workspace = rebuildWorkspaceDir
+	rebuild.sourceDateEpoch = time.Now().AddDate(1, 1, 1).Add(time.Hour + time.Minute + time.Second)
+
+	log.Printf("Rebuilding the artifacts to check their reproducibility.")
+	rebuilt, err := rebuild.BuildArtifacts(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("rebuilding the artifacts: %w", err)
+	}
+
+	report := &VerificationReport{
+		SourceRepo:   db.config.SourceRepo,
+		SourceCommit: db.config.SourceDigest.Value,
+		BuilderImage: builderImage(db.config).URI,
+	}
+	report.compare(artifacts, rebuilt)
+	return report, nil
+}
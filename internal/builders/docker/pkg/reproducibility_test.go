@@ -0,0 +1,131 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_DockerBuild_CheckReproducibility(t *testing.T) {
+	tests := []struct {
+		name string
+		// Shell script run instead of the build containers, in the checkout.
+		script string
+		// Environment of the build config.
+		env  string
+		want []string
+	}{
+		{
+			name:   "reproducible",
+			script: "echo app > app; echo lib > lib",
+		},
+		{
+			name:   "timestamp",
+			script: "echo app > app; echo ${SOURCE_DATE_EPOCH:-$(date +%s)} > lib",
+			want:   []string{"lib"},
+		},
+		{
+			name:   "pinned timestamp",
+			script: "echo app > app; echo ${SOURCE_DATE_EPOCH:-$(date +%s)} > lib",
+			env:    "env = { SOURCE_DATE_EPOCH = \"1700000000\" }\n",
+		},
+		{
+			name:   "workspace path",
+			script: "pwd > app; echo lib > lib",
+			want:   []string{"app"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := chdirTemp(t)
+			src := filepath.Join(dir, "src")
+			writeFiles(t, src, "main.go")
+			config := "command = [\"make\"]\nartifact_paths = [\"app\", \"lib\"]\n" + tt.env
+			if err := os.WriteFile(filepath.Join(src, "config.toml"), []byte(config), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			hash, err := gitTreeHash(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rt := &testRuntime{run: []string{"sh", "-c", tt.script}}
+			dbc := &DockerBuildConfig{
+				SourceRepo:      "file://" + filepath.ToSlash(src),
+				SourceDigest:    Digest{Alg: gitTreeAlg, Value: hash},
+				BuilderImage:    DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+				BuildConfigPath: "config.toml",
+				ForceCheckout:   true,
+			}
+			f, err := newFetcher(dbc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			db, err := (&Builder{repoFetcher: f, runtime: rt, config: *dbc}).SetUpBuildState(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer db.RepoInfo.Cleanup()
+			artifacts, err := db.BuildArtifacts(context.Background(), "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			report, err := db.CheckReproducibility(context.Background(), artifacts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, report.NonReproducible()); diff != "" {
+				t.Errorf("unexpected non-reproducible artifacts (-want +got):\n%s", diff)
+			}
+			if got, want := report.Reproducible, len(tt.want) == 0; got != want {
+				t.Errorf("unexpected result, got: %t, want: %t", got, want)
+			}
+
+			// The second build runs with a different workspace, and the
+			// working directory of the first build is restored.
+			if !slices.Contains(rt.commands[1], "--workdir="+rebuildWorkspaceDir) {
+				t.Errorf("unexpected rebuild command: %q", rt.commands[1])
+			}
+			// Only the second build runs with an injected SOURCE_DATE_EPOCH,
+			// unless the build config sets it.
+			for i, c := range rt.commands {
+				var n int
+				for _, f := range c {
+					if strings.HasPrefix(f, "--env="+sourceDateEpochEnv+"=") {
+						n++
+					}
+				}
+				if want := i == 1 || tt.env != ""; (n == 1) != want || n > 1 {
+					t.Errorf("unexpected SOURCE_DATE_EPOCH in command %d: %q", i, c)
+				}
+			}
+			if got, err := os.Getwd(); err != nil || got != wd {
+				t.Errorf("unexpected working directory: %q, %v", got, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the container runtimes that can be used for running the
+// build steps.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	// DockerRuntime is the name of the Docker container runtime.
+	DockerRuntime = "docker"
+
+	// PodmanRuntime is the name of the Podman container runtime.
+	PodmanRuntime = "podman"
+
+	// NerdctlRuntime is the name of the nerdctl (containerd) container runtime.
+	NerdctlRuntime = "nerdctl"
+)
+
+// errContainerRuntime indicates an unsupported container runtime.
+var errContainerRuntime = errors.New("container runtime")
+
+// runtimeWaitDelay is the time to wait for the output of a runtime command
+// after it is killed because its context is done, e.g., if the CLI started
+// processes that keep its output open.
+const runtimeWaitDelay = 10 * time.Second
+
+// ContainerRuntime runs build steps in containers.
+type ContainerRuntime interface {
+	// Name returns the name of the runtime, e.g., `docker`.
+	Name() string
+
+	// Version returns the version of the runtime.
+	Version(ctx context.Context) (string, error)
+
+	// RunCommand returns the command that runs the given command in a new
+	// container with the given name from the image, using the given `run`
+	// flags. The container is stopped and removed if ctx is done before the
+	// command completes.
+	RunCommand(ctx context.Context, name string, flags []string, image string, command []string) *exec.Cmd
+
+	// InspectImage returns the local image with the given reference or ID.
+	InspectImage(ctx context.Context, image string) (*ImageInfo, error)
+
+	// PullImage pulls the image with the given reference from its registry.
+	// The pull is stopped if ctx is done before it completes.
+	PullImage(ctx context.Context, image string) error
+
+	// LoadImage loads the images of the OCI image layout tarball read from r.
+	// The load is stopped if ctx is done before it completes.
+	LoadImage(ctx context.Context, r io.Reader) error
+
+	// BuildImage builds an image from the Dockerfile, using the build context
+	// directory, and returns the ID of the image.
+	BuildImage(ctx context.Context, dockerfile, contextDir string) (string, error)
+}
+
+// RuntimeInfo describes the container runtime used for a build. It is
+// recorded in the internal parameters of the provenance.
+type RuntimeInfo struct {
+	// Name of the runtime, e.g., `docker`.
+	Name string `json:"name"`
+
+	// Version of the runtime, if it could be determined.
+	Version string `json:"version,omitempty"`
+}
+
+// cliRuntime is a ContainerRuntime that uses a Docker-compatible CLI.
+type cliRuntime struct {
+	name string
+}
+
+// NewContainerRuntime returns the ContainerRuntime with the given name. The
+// Docker runtime is returned if the name is empty.
+func NewContainerRuntime(name string) (ContainerRuntime, error) {
+	switch name {
+	case "":
+		return &cliRuntime{name: DockerRuntime}, nil
+	case DockerRuntime, PodmanRuntime, NerdctlRuntime:
+		return &cliRuntime{name: name}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported runtime %q, want one of %q, %q, %q",
+			errContainerRuntime, name, DockerRuntime, PodmanRuntime, NerdctlRuntime)
+	}
+}
+
+// Name implements ContainerRuntime.Name.
+func (r *cliRuntime) Name() string {
+	return r.name
+}
+
+// command returns the command running the CLI of the runtime with the
+// arguments. The command is killed if ctx is done before it completes.
+func (r *cliRuntime) command(ctx context.Context, args ...string) *exec.Cmd {
+	//#nosec G204 -- The runtime name is one of the supported runtimes.
+	cmd := exec.CommandContext(ctx, r.name, args...)
+	cmd.WaitDelay = runtimeWaitDelay
+	return cmd
+}
+
+// Version implements ContainerRuntime.Version.
+func (r *cliRuntime) Version(ctx context.Context) (string, error) {
+	out, err := r.command(ctx, "version", "--format", "{{.Client.Version}}").Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: getting the %s version: %w", errContainerRuntime, r.name, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// RunCommand implements ContainerRuntime.RunCommand.
+func (r *cliRuntime) RunCommand(ctx context.Context, name string, flags []string, image string, command []string) *exec.Cmd {
+	args := []string{"run", "--name=" + name}
+	args = append(args, flags...)
+	args = append(args, image)
+	args = append(args, command...)
+	cmd := r.command(ctx, args...)
+	cmd.Cancel = func() error {
+		// Killing the CLI does not stop the container, so it is removed first.
+		//#nosec G204 -- The name is generated by the builder.
+		if out, err := exec.Command(r.name, "rm", "--force", name).CombinedOutput(); err != nil {
+			log.Printf("Couldn't remove the container %q: %v: %s", name, err, out)
+		}
+		return cmd.Process.Kill()
+	}
+	return cmd
+}
+
+// InspectImage implements ContainerRuntime.InspectImage.
+func (r *cliRuntime) InspectImage(ctx context.Context, image string) (*ImageInfo, error) {
+	out, err := r.command(ctx, "image", "inspect", image).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: inspecting the image %q: %w", errContainerRuntime, image, err)
+	}
+	var infos []ImageInfo
+	if err := json.Unmarshal(out, &infos); err != nil {
+		return nil, fmt.Errorf("%w: parsing the image %q: %w", errContainerRuntime, image, err)
+	}
+	if len(infos) != 1 {
+		return nil, fmt.Errorf("%w: got %d images for %q", errContainerRuntime, len(infos), image)
+	}
+	return &infos[0], nil
+}
+
+// PullImage implements ContainerRuntime.PullImage.
+func (r *cliRuntime) PullImage(ctx context.Context, image string) error {
+	if out, err := r.command(ctx, "pull", image).CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: pulling the image %q: %w: %s", errContainerRuntime, image, err, out)
+	}
+	return nil
+}
+
+// LoadImage implements ContainerRuntime.LoadImage.
+func (r *cliRuntime) LoadImage(ctx context.Context, rd io.Reader) error {
+	cmd := r.command(ctx, "load")
+	cmd.Stdin = rd
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%w: loading the images: %w: %s", errContainerRuntime, err, out)
+	}
+	return nil
+}
+
+// BuildImage implements ContainerRuntime.BuildImage.
+func (r *cliRuntime) BuildImage(ctx context.Context, dockerfile, contextDir string) (string, error) {
+	iidFile, err := os.CreateTemp("", "iid-*")
+	if err != nil {
+		return "", fmt.Errorf("couldn't create temp file: %v", err)
+	}
+	iidFile.Close()
+	defer os.Remove(iidFile.Name())
+
+	cmd := r.command(ctx, "build", "--file", dockerfile, "--iidfile", iidFile.Name(), contextDir)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", fmt.Errorf("%w: building the image from %q: %w: %s", errContainerRuntime, dockerfile, err, out)
+	}
+	id, err := os.ReadFile(iidFile.Name())
+	if err != nil {
+		return "", fmt.Errorf("%w: reading the image ID: %w", errContainerRuntime, err)
+	}
+	return strings.TrimSpace(string(id)), nil
+}
+
+// runtimeInfo returns the RuntimeInfo of the runtime. The version is omitted
+// if it cannot be determined, e.g., when the runtime is not installed.
+func runtimeInfo(ctx context.Context, r ContainerRuntime) *RuntimeInfo {
+	info := &RuntimeInfo{Name: r.Name()}
+	v, err := r.Version(ctx)
+	if err != nil {
+		log.Printf("Couldn't determine the container runtime version: %v", err)
+		return info
+	}
+	info.Version = v
+	return info
+}
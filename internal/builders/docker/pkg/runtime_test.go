@@ -0,0 +1,435 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// testRuntime is a ContainerRuntime that records the commands it creates.
+// builtImageID is the ID of the images built by testRuntime.
+const builtImageID = "sha256:b1d7"
+
+type testRuntime struct {
+	version    string
+	versionErr error
+	commands   [][]string
+	// names are the names of the containers of the commands.
+	names []string
+	// run is the command that is run instead of the containers. It defaults
+	// to `true`.
+	run []string
+	// images are the local images. If it is nil, every image is available
+	// locally, with its reference as ID and repo digest.
+	images map[string]*ImageInfo
+	// pulled are the pulled images, which are added to images.
+	pulled []string
+	// pullable are the images that can be pulled.
+	pullable map[string]*ImageInfo
+	// loaded are the contents of the loaded tarballs.
+	loaded [][]byte
+	// loadable are the images that are available after loading a tarball.
+	loadable map[string]*ImageInfo
+	// built are the Dockerfiles of the built images.
+	built []string
+	// buildErr is returned by BuildImage.
+	buildErr error
+	// blockPull makes PullImage block until its context is done.
+	blockPull bool
+}
+
+// Name implements ContainerRuntime.Name.
+func (r *testRuntime) Name() string {
+	return "test"
+}
+
+// Version implements ContainerRuntime.Version.
+func (r *testRuntime) Version(_ context.Context) (string, error) {
+	return r.version, r.versionErr
+}
+
+// RunCommand implements ContainerRuntime.RunCommand.
+func (r *testRuntime) RunCommand(ctx context.Context, name string, flags []string, image string, command []string) *exec.Cmd {
+	args := append(append(append([]string{}, flags...), image), command...)
+	r.commands = append(r.commands, args)
+	r.names = append(r.names, name)
+	run := r.run
+	if len(run) == 0 {
+		run = []string{"true"}
+	}
+	cmd := exec.CommandContext(ctx, run[0], run[1:]...)
+	// The environment of the container is set for the command.
+	cmd.Env = os.Environ()
+	for _, f := range flags {
+		if env, ok := strings.CutPrefix(f, "--env="); ok {
+			cmd.Env = append(cmd.Env, env)
+		}
+	}
+	return cmd
+}
+
+// InspectImage implements ContainerRuntime.InspectImage.
+func (r *testRuntime) InspectImage(_ context.Context, image string) (*ImageInfo, error) {
+	if r.images == nil {
+		return &ImageInfo{ID: image, RepoDigests: []string{image}}, nil
+	}
+	info, ok := r.images[image]
+	if !ok {
+		return nil, fmt.Errorf("no such image: %s", image)
+	}
+	return info, nil
+}
+
+// PullImage implements ContainerRuntime.PullImage.
+func (r *testRuntime) PullImage(ctx context.Context, image string) error {
+	if r.blockPull {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	info, ok := r.pullable[image]
+	if !ok {
+		return fmt.Errorf("pull access denied for %s", image)
+	}
+	r.pulled = append(r.pulled, image)
+	r.addImage(image, info)
+	return nil
+}
+
+// LoadImage implements ContainerRuntime.LoadImage.
+func (r *testRuntime) LoadImage(_ context.Context, tarball io.Reader) error {
+	content, err := io.ReadAll(tarball)
+	if err != nil {
+		return err
+	}
+	r.loaded = append(r.loaded, content)
+	for image, info := range r.loadable {
+		r.addImage(image, info)
+	}
+	return nil
+}
+
+// BuildImage implements ContainerRuntime.BuildImage. The ID of the image is
+// builtImageID.
+func (r *testRuntime) BuildImage(_ context.Context, dockerfile, _ string) (string, error) {
+	if r.buildErr != nil {
+		return "", r.buildErr
+	}
+	r.built = append(r.built, dockerfile)
+	return builtImageID, nil
+}
+
+func (r *testRuntime) addImage(image string, info *ImageInfo) {
+	if r.images == nil {
+		r.images = map[string]*ImageInfo{}
+	}
+	r.images[image] = info
+}
+
+func Test_NewContainerRuntime(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expected error
+		name     string
+		runtime  string
+		want     string
+	}{
+		{
+			name: "default",
+			want: DockerRuntime,
+		},
+		{
+			name:    "docker",
+			runtime: DockerRuntime,
+			want:    DockerRuntime,
+		},
+		{
+			name:    "podman",
+			runtime: PodmanRuntime,
+			want:    PodmanRuntime,
+		},
+		{
+			name:    "nerdctl",
+			runtime: NerdctlRuntime,
+			want:    NerdctlRuntime,
+		},
+		{
+			name:     "unsupported",
+			runtime:  "lxc",
+			expected: errContainerRuntime,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			rt, err := NewContainerRuntime(tt.runtime)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if err != nil {
+				return
+			}
+			if got := rt.Name(); got != tt.want {
+				t.Errorf("unexpected name, got: %q, want: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_cliRuntime_RunCommand(t *testing.T) {
+	t.Parallel()
+
+	rt, err := NewContainerRuntime(PodmanRuntime)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cmd := rt.RunCommand(context.Background(), "build", []string{"--rm"}, "bash@sha256:abcd", []string{"echo", "hello"})
+	want := []string{"podman", "run", "--name=build", "--rm", "bash@sha256:abcd", "echo", "hello"}
+	if diff := cmp.Diff(want, cmd.Args); diff != "" {
+		t.Errorf("unexpected args (-want +got):\n%s", diff)
+	}
+}
+
+func Test_runtimeInfo(t *testing.T) {
+	t.Parallel()
+
+	got := runtimeInfo(context.Background(), &testRuntime{version: "1.2.3"})
+	if diff := cmp.Diff(&RuntimeInfo{Name: "test", Version: "1.2.3"}, got); diff != "" {
+		t.Errorf("unexpected info (-want +got):\n%s", diff)
+	}
+
+	got = runtimeInfo(context.Background(), &testRuntime{versionErr: errContainerRuntime})
+	if diff := cmp.Diff(&RuntimeInfo{Name: "test"}, got); diff != "" {
+		t.Errorf("unexpected info (-want +got):\n%s", diff)
+	}
+}
+
+func Test_CreateBuildDefinition_runtime(t *testing.T) {
+	t.Parallel()
+
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			SourceRepo:   "git+https://github.com/slsa-framework/slsa-github-generator",
+			SourceDigest: Digest{Alg: "sha1", Value: "cf5804b5c6f1a4b2a0b03401a487dfdfbe3a5f00"},
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		buildConfig: &BuildConfig{Command: []string{"make"}},
+		runtimeInfo: &RuntimeInfo{Name: PodmanRuntime, Version: "4.9.3"},
+	}
+
+	got := db.CreateBuildDefinition().InternalParameters
+	want := map[string]any{
+		ContainerRuntimeKey: RuntimeInfo{Name: PodmanRuntime, Version: "4.9.3"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected internal parameters (-want +got):\n%s", diff)
+	}
+}
+
+func Test_runDockerRun_runtime(t *testing.T) {
+	const codegenImage = "codegen@sha256:1111"
+
+	rt := &testRuntime{}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		buildConfig: &BuildConfig{
+			Env: map[string]string{"A": "1"},
+			Steps: []BuildStep{
+				{Command: []string{"codegen"}, BuilderImage: codegenImage},
+				{Command: []string{"make"}},
+			},
+		},
+		runtime: rt,
+	}
+	if err := runDockerRun(context.Background(), db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := len(rt.commands), 2; got != want {
+		t.Fatalf("unexpected number of commands, got: %d, want: %d", got, want)
+	}
+	for i, want := range [][]string{
+		{"--env=A=1", codegenImage, "codegen"},
+		{"--env=A=1", "bash@sha256:abcd", "make"},
+	} {
+		// Skip the default flags, which depend on the working directory.
+		got := rt.commands[i][3:]
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected command %d (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func Test_runDockerRun_hermetic(t *testing.T) {
+	const fetchImage = "fetch@sha256:2222"
+
+	rt := &testRuntime{}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		buildConfig: &BuildConfig{
+			Command:  []string{"make"},
+			Hermetic: true,
+			Prefetch: &BuildStep{Command: []string{"make", "deps"}, BuilderImage: fetchImage},
+		},
+		runtime: rt,
+	}
+	if err := runDockerRun(context.Background(), db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got, want := len(rt.commands), 2; got != want {
+		t.Fatalf("unexpected number of commands, got: %d, want: %d", got, want)
+	}
+	for i, want := range [][]string{
+		{fetchImage, "make", "deps"},
+		{
+			"--network=none", "--read-only", "--cap-drop=ALL", "--security-opt=no-new-privileges",
+			"bash@sha256:abcd", "make",
+		},
+	} {
+		// Skip the default flags, which depend on the working directory.
+		got := rt.commands[i][3:]
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected command %d (-want +got):\n%s", i, diff)
+		}
+	}
+
+	got := db.CreateBuildDefinition()
+	if diff := cmp.Diff(map[string]any{HermeticKey: true}, got.InternalParameters); diff != "" {
+		t.Errorf("unexpected internal parameters (-want +got):\n%s", diff)
+	}
+	if got, want := len(got.ResolvedDependencies), 2; got != want {
+		t.Errorf("unexpected number of resolved dependencies, got: %d, want: %d", got, want)
+	}
+}
+
+func Test_runDockerRun_limits(t *testing.T) {
+	rt := &testRuntime{}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		buildConfig: &BuildConfig{
+			Command:   []string{"make"},
+			CPUs:      "2",
+			Memory:    "1g",
+			PidsLimit: 512,
+		},
+		runtime: rt,
+	}
+	if err := runDockerRun(context.Background(), db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"--cpus=2", "--memory=1g", "--pids-limit=512", "bash@sha256:abcd", "make"}
+	if diff := cmp.Diff(want, rt.commands[0][3:]); diff != "" {
+		t.Errorf("unexpected command (-want +got):\n%s", diff)
+	}
+}
+
+func Test_runDockerRun_timeout(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeout  string
+		cancel   bool
+		expected error
+	}{
+		{
+			name:     "timeout",
+			timeout:  "100ms",
+			expected: errBuildTimeout,
+		},
+		{
+			name:     "canceled",
+			cancel:   true,
+			expected: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rt := &testRuntime{run: []string{"sleep", "10"}}
+			db := &DockerBuild{
+				config: &DockerBuildConfig{
+					BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+				},
+				buildConfig: &BuildConfig{
+					Steps:   []BuildStep{{Command: []string{"make"}}, {Command: []string{"make", "test"}}},
+					Timeout: tt.timeout,
+				},
+				runtime: rt,
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancel {
+				time.AfterFunc(100*time.Millisecond, cancel)
+			}
+			start := time.Now()
+			err := runDockerRun(ctx, db)
+			if diff := cmp.Diff(tt.expected, err, cmpopts.EquateErrors()); diff != "" {
+				t.Errorf("unexpected error (-want +got):\n%s", diff)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("the step was not stopped, it ran for %v", elapsed)
+			}
+			// The remaining steps are not run.
+			if len(rt.commands) != 1 {
+				t.Errorf("unexpected commands: %q", rt.commands)
+			}
+		})
+	}
+}
+
+func Test_runDockerRun_timeoutPull(t *testing.T) {
+	// The image is not available locally, and pulling it hangs.
+	rt := &testRuntime{images: map[string]*ImageInfo{}, blockPull: true}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+		},
+		buildConfig: &BuildConfig{
+			Command: []string{"make"},
+			Timeout: "100ms",
+		},
+		runtime: rt,
+	}
+
+	start := time.Now()
+	err := runDockerRun(context.Background(), db)
+	if diff := cmp.Diff(errBuildTimeout, err, cmpopts.EquateErrors()); diff != "" {
+		t.Errorf("unexpected error (-want +got):\n%s", diff)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("the pull was not stopped, it ran for %v", elapsed)
+	}
+	if len(rt.commands) != 0 {
+		t.Errorf("unexpected commands: %q", rt.commands)
+	}
+}
@@ -0,0 +1,216 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the support for the secrets of a build, such as registry
+// tokens, which are mounted in the containers of the build steps but are never
+// recorded in the provenance.
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// errSecret indicates an invalid or missing secret.
+var errSecret = errors.New("secret")
+
+// secretsDir is the directory in the container where the secrets are mounted.
+const secretsDir = "/run/secrets"
+
+// secretsTempDir is the directory of the host where the values of the secrets
+// are written for the duration of the build. It is a tmpfs on Linux, so that
+// the values are never written to disk. Builds with secrets fail on hosts
+// that do not have it.
+const secretsTempDir = "/dev/shm"
+
+// secretNameRegex matches valid secret names.
+var secretNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// Secret is the value of a secret required by the build config. The value is
+// read from either a file or an environment variable.
+type Secret struct {
+	// Name of the secret in the build config.
+	Name string
+	// Absolute path of the file containing the value.
+	Path string
+	// Name of the environment variable containing the value.
+	Env string
+}
+
+// ParseSecrets parses the secrets given as `id=NAME,src=PATH` or
+// `id=NAME,env=VARIABLE`, and checks that their values are available.
+func ParseSecrets(specs []string) ([]Secret, error) {
+	var secrets []Secret
+	for _, spec := range specs {
+		s, err := parseSecret(spec)
+		if err != nil {
+			return nil, err
+		}
+		if slices.ContainsFunc(secrets, func(o Secret) bool { return o.Name == s.Name }) {
+			return nil, fmt.Errorf("%w: %q is given more than once", errSecret, s.Name)
+		}
+		secrets = append(secrets, *s)
+	}
+	return secrets, nil
+}
+
+// parseSecret parses a single secret. The values of secrets must never be
+// included in the errors.
+func parseSecret(spec string) (*Secret, error) {
+	var s Secret
+	for i, field := range strings.Split(spec, ",") {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("%w: malformed field %d, want key=value", errSecret, i+1)
+		}
+		switch key {
+		case "id":
+			s.Name = value
+		case "src", "source":
+			s.Path = value
+		case "env":
+			s.Env = value
+		default:
+			return nil, fmt.Errorf("%w: unknown field %q", errSecret, key)
+		}
+	}
+	if !secretNameRegex.MatchString(s.Name) {
+		return nil, fmt.Errorf("%w: invalid name %q", errSecret, s.Name)
+	}
+
+	switch {
+	case s.Path != "" && s.Env != "":
+		return nil, fmt.Errorf("%w: only one of src and env can be set for %q", errSecret, s.Name)
+	case s.Path != "":
+		p, err := filepath.Abs(s.Path)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid path of %q: %v", errSecret, s.Name, err)
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", errSecret, s.Name, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%w: %q: %q is not a regular file", errSecret, s.Name, p)
+		}
+		s.Path = p
+	case s.Env != "":
+		if _, ok := os.LookupEnv(s.Env); !ok {
+			return nil, fmt.Errorf("%w: %q: environment variable %q is not set", errSecret, s.Name, s.Env)
+		}
+	default:
+		return nil, fmt.Errorf("%w: one of src and env must be set for %q", errSecret, s.Name)
+	}
+	return &s, nil
+}
+
+// value returns the value of the secret.
+func (s *Secret) value() ([]byte, error) {
+	if s.Env != "" {
+		v, ok := os.LookupEnv(s.Env)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q: environment variable %q is not set", errSecret, s.Name, s.Env)
+		}
+		return []byte(v), nil
+	}
+	v, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", errSecret, s.Name, err)
+	}
+	return v, nil
+}
+
+// validateSecretNames checks that the names of the secrets of the build config
+// are valid and distinct.
+func validateSecretNames(names []string) error {
+	for i, name := range names {
+		if !secretNameRegex.MatchString(name) {
+			return fmt.Errorf("invalid secret name %q", name)
+		}
+		if slices.Contains(names[:i], name) {
+			return fmt.Errorf("secret %q is declared more than once", name)
+		}
+	}
+	return nil
+}
+
+// checkSecrets checks that the secrets supplied to the build are exactly the
+// ones declared in the build config. Secrets are never taken implicitly from
+// the environment, so every declared secret must be supplied explicitly.
+func checkSecrets(declared []string, supplied []Secret) error {
+	for _, name := range declared {
+		if !slices.ContainsFunc(supplied, func(s Secret) bool { return s.Name == name }) {
+			return fmt.Errorf("%w: the build config requires the secret %q, which must be supplied with --secret",
+				errSecret, name)
+		}
+	}
+	for _, s := range supplied {
+		if !slices.Contains(declared, s.Name) {
+			return fmt.Errorf("%w: %q is not declared in the secrets of the build config", errSecret, s.Name)
+		}
+	}
+	return nil
+}
+
+// mountSecrets writes the values of the secrets to a temporary directory under
+// tmpfs, which must be a tmpfs directory, and returns the flags of `docker run`
+// mounting them read-only in the container, and a function removing the
+// temporary directory.
+func mountSecrets(secrets []Secret, tmpfs string) ([]string, func(), error) {
+	if len(secrets) == 0 {
+		return nil, func() {}, nil
+	}
+	// The values must not be written to disk, so there is no fallback to
+	// the default temporary directory.
+	if info, err := os.Stat(tmpfs); err != nil || !info.IsDir() {
+		return nil, func() {}, fmt.Errorf("%w: the tmpfs directory %q for the secrets is not available", errSecret, tmpfs)
+	}
+	// The directory is only accessible by the current user, while the files
+	// are readable by any user of the container.
+	dir, err := os.MkdirTemp(tmpfs, "slsa-secrets-")
+	if err != nil {
+		return nil, func() {}, fmt.Errorf("%w: creating the secrets directory: %v", errSecret, err)
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("couldn't remove the secrets directory %q: %v", dir, err)
+		}
+	}
+
+	var flags []string
+	for _, s := range secrets {
+		v, err := s.value()
+		if err != nil {
+			cleanup()
+			return nil, func() {}, err
+		}
+		p := filepath.Join(dir, s.Name)
+		//#nosec G306 -- The directory is private to the current user.
+		if err := os.WriteFile(p, v, 0o444); err != nil {
+			cleanup()
+			return nil, func() {}, fmt.Errorf("%w: writing %q: %v", errSecret, s.Name, err)
+		}
+		flags = append(flags, fmt.Sprintf("--mount=type=bind,source=%s,target=%s,readonly",
+			p, path.Join(secretsDir, s.Name)))
+	}
+	return flags, cleanup, nil
+}
@@ -0,0 +1,295 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// secretValue is the value of the secrets in the tests, which must never be
+// part of the errors, commands or provenance.
+const secretValue = "hunter2"
+
+func Test_ParseSecrets(t *testing.T) {
+	t.Setenv("TEST_SECRET_TOKEN", secretValue)
+	p := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(p, []byte(secretValue), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		specs []string
+		want  []Secret
+		err   error
+	}{
+		{
+			name: "no secrets",
+		},
+		{
+			name:  "file and env",
+			specs: []string{"id=key,src=" + p, "id=token,env=TEST_SECRET_TOKEN"},
+			want: []Secret{
+				{Name: "key", Path: p},
+				{Name: "token", Env: "TEST_SECRET_TOKEN"},
+			},
+		},
+		{
+			name:  "file and env set",
+			specs: []string{"id=token,src=" + p + ",env=TEST_SECRET_TOKEN"},
+			err:   errSecret,
+		},
+		{
+			name:  "no value",
+			specs: []string{"id=token"},
+			err:   errSecret,
+		},
+		{
+			name:  "invalid name",
+			specs: []string{"id=../token,env=TEST_SECRET_TOKEN"},
+			err:   errSecret,
+		},
+		{
+			name:  "unknown field",
+			specs: []string{"id=token,type=env"},
+			err:   errSecret,
+		},
+		{
+			name:  "value instead of source",
+			specs: []string{"id=token," + secretValue},
+			err:   errSecret,
+		},
+		{
+			name:  "missing file",
+			specs: []string{"id=token,src=" + filepath.Join(filepath.Dir(p), "missing")},
+			err:   errSecret,
+		},
+		{
+			name:  "directory",
+			specs: []string{"id=token,src=" + filepath.Dir(p)},
+			err:   errSecret,
+		},
+		{
+			name:  "unset env",
+			specs: []string{"id=token,env=TEST_SECRET_UNSET"},
+			err:   errSecret,
+		},
+		{
+			name:  "duplicate",
+			specs: []string{"id=token,src=" + p, "id=token,env=TEST_SECRET_TOKEN"},
+			err:   errSecret,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSecrets(tt.specs)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.err, cmpopts.EquateErrors()))
+			}
+			if err != nil && strings.Contains(err.Error(), secretValue) {
+				t.Errorf("the error contains the value of the secret: %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected secrets (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_LoadBuildConfigFromFile_secrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:   "secrets",
+			config: "command = [\"make\"]\nsecrets = [\"npm_token\", \"signing.key\"]\n",
+			want:   []string{"npm_token", "signing.key"},
+		},
+		{
+			name:    "invalid name",
+			config:  "command = [\"make\"]\nsecrets = [\"npm/token\"]\n",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate",
+			config:  "command = [\"make\"]\nsecrets = [\"npm_token\", \"npm_token\"]\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := loadBuildConfigFromString(t, tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got.Secrets); diff != "" {
+				t.Errorf("unexpected secrets (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_checkSecrets(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		declared []string
+		supplied []Secret
+		err      error
+	}{
+		{
+			name: "no secrets",
+		},
+		{
+			name:     "all supplied",
+			declared: []string{"key", "token"},
+			supplied: []Secret{{Name: "token", Env: "TOKEN"}, {Name: "key", Path: "/key"}},
+		},
+		{
+			name:     "not supplied",
+			declared: []string{"key", "token"},
+			supplied: []Secret{{Name: "token", Env: "TOKEN"}},
+			err:      errSecret,
+		},
+		{
+			name:     "not declared",
+			supplied: []Secret{{Name: "token", Env: "TOKEN"}},
+			err:      errSecret,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := checkSecrets(tt.declared, tt.supplied)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("unexpected error: %v", cmp.Diff(err, tt.err, cmpopts.EquateErrors()))
+			}
+		})
+	}
+}
+
+func Test_runDockerRun_secrets(t *testing.T) {
+	t.Setenv("TEST_SECRET_TOKEN", secretValue)
+
+	rt := &testRuntime{}
+	db := &DockerBuild{
+		config: &DockerBuildConfig{
+			BuilderImage: DockerImage{Name: "bash", Digest: Digest{Alg: "sha256", Value: "abcd"}},
+			Secrets:      []Secret{{Name: "token", Env: "TEST_SECRET_TOKEN"}},
+		},
+		buildConfig: &BuildConfig{
+			Steps: []BuildStep{
+				{Command: []string{"npm", "ci"}},
+				{Command: []string{"npm", "run", "build"}},
+			},
+			Secrets: []string{"token"},
+		},
+		runtime: rt,
+	}
+	if err := runDockerRun(context.Background(), db); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The secret is mounted in all the steps, and removed after the build.
+	if len(rt.commands) != 2 {
+		t.Fatalf("unexpected commands: %q", rt.commands)
+	}
+	for _, c := range rt.commands {
+		var source string
+		for _, arg := range c {
+			if strings.Contains(arg, secretValue) {
+				t.Errorf("the command contains the value of the secret: %q", c)
+			}
+			if mount, ok := strings.CutPrefix(arg, "--mount=type=bind,source="); ok {
+				var target string
+				source, target, _ = strings.Cut(mount, ",")
+				if target != "target=/run/secrets/token,readonly" {
+					t.Errorf("unexpected mount: %q", arg)
+				}
+			}
+		}
+		if source == "" {
+			t.Fatalf("the secret is not mounted: %q", c)
+		}
+		if _, err := os.Stat(source); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("the secret is not removed: %v", err)
+		}
+	}
+
+	// Only the name of the secret is recorded in the provenance.
+	b, err := json.Marshal(db.CreateBuildDefinition())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), secretValue) || !strings.Contains(string(b), `"secrets":["token"]`) {
+		t.Errorf("unexpected build definition: %s", b)
+	}
+}
+
+func Test_mountSecrets_tmpfs(t *testing.T) {
+	t.Setenv("TEST_SECRET_TOKEN", secretValue)
+	secrets := []Secret{{Name: "token", Env: "TEST_SECRET_TOKEN"}}
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The secrets are never written to another directory when the tmpfs
+	// directory is not available.
+	for _, tmpfs := range []string{filepath.Join(dir, "missing"), file} {
+		flags, cleanup, err := mountSecrets(secrets, tmpfs)
+		cleanup()
+		if !errors.Is(err, errSecret) {
+			t.Errorf("unexpected error for %q: %v", tmpfs, cmp.Diff(err, errSecret, cmpopts.EquateErrors()))
+		}
+		if flags != nil {
+			t.Errorf("unexpected flags for %q: %q", tmpfs, flags)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("unexpected files: %v", entries)
+	}
+
+	flags, cleanup, err := mountSecrets(secrets, dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer cleanup()
+	if len(flags) != 1 || !strings.HasPrefix(flags[0], "--mount=type=bind,source="+dir+string(filepath.Separator)) {
+		t.Errorf("unexpected flags: %q", flags)
+	}
+}
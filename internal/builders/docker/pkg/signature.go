@@ -0,0 +1,241 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the verification of the signature of a provenance before
+// it is used for rebuilding the artifacts.
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	sigstoreBundle "github.com/sigstore/sigstore-go/pkg/bundle"
+	"github.com/sigstore/sigstore-go/pkg/fulcio/certificate"
+	sigstoreRoot "github.com/sigstore/sigstore-go/pkg/root"
+	sigstoreVerify "github.com/sigstore/sigstore-go/pkg/verify"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+
+	"github.com/slsa-framework/slsa-github-generator/signing/envelope"
+)
+
+const (
+	// DefaultBuilderIdentityRegexp matches the identity of the workflow of the
+	// container-based builder, at a release tag.
+	DefaultBuilderIdentityRegexp = `^https://github\.com/slsa-framework/slsa-github-generator/\.github/workflows/builder_container-based_slsa3\.yml@refs/tags/v[0-9]+\.[0-9]+\.[0-9]+$`
+
+	// DefaultBuilderOIDCIssuer is the OIDC issuer of GitHub Actions.
+	DefaultBuilderOIDCIssuer = "https://token.actions.githubusercontent.com"
+)
+
+var (
+	// errUnsigned indicates that the provenance is not signed.
+	errUnsigned = errors.New("unsigned provenance")
+
+	// errSignature indicates that the signature of the provenance is invalid.
+	errSignature = errors.New("invalid provenance signature")
+)
+
+// ProvenanceVerifier verifies the signature of a provenance, which can be a
+// Sigstore bundle or a DSSE envelope with the signing certificate, against
+// the expected builder identity.
+type ProvenanceVerifier struct {
+	identity        sigstoreVerify.CertificateIdentity
+	trustedRootPath string
+	trustedMaterial sigstoreRoot.TrustedMaterial
+	allowUnsigned   bool
+}
+
+// NewProvenanceVerifier creates a new ProvenanceVerifier for the builder
+// identity given by a regular expression matching the certificate subject
+// alternative name, and the OIDC issuer. The Sigstore trusted root is read
+// from trustedRootPath, or fetched using TUF if it is empty. Unsigned
+// provenance, and DSSE envelopes without a transparency log entry or a signed
+// timestamp, are accepted only if allowUnsigned is true.
+func NewProvenanceVerifier(identityRegexp, oidcIssuer, trustedRootPath string, allowUnsigned bool) (*ProvenanceVerifier, error) {
+	identity, err := sigstoreVerify.NewShortCertificateIdentity(oidcIssuer, "", "", identityRegexp)
+	if err != nil {
+		return nil, fmt.Errorf("creating the builder identity: %w", err)
+	}
+	return &ProvenanceVerifier{
+		identity:        identity,
+		trustedRootPath: trustedRootPath,
+		allowUnsigned:   allowUnsigned,
+	}, nil
+}
+
+// signedContent contains the fields used to detect the format of the
+// provenance.
+type signedContent struct {
+	MediaType   string `json:"mediaType"`
+	PayloadType string `json:"payloadType"`
+	Type        string `json:"_type"`
+}
+
+// Verify verifies the signature of the provenance, and returns the signed
+// in-toto statement.
+func (v *ProvenanceVerifier) Verify(content []byte) ([]byte, error) {
+	var sc signedContent
+	if err := json.Unmarshal(content, &sc); err != nil {
+		return nil, fmt.Errorf("could not unmarshal the provenance file: %w", err)
+	}
+
+	switch {
+	case strings.HasPrefix(sc.MediaType, "application/vnd.dev.sigstore.bundle"):
+		var b sigstoreBundle.Bundle
+		if err := b.UnmarshalJSON(content); err != nil {
+			return nil, fmt.Errorf("%w: parsing the Sigstore bundle: %w", errSignature, err)
+		}
+		return v.verifySignedEntity(&b)
+	case sc.PayloadType != "":
+		// A DSSE envelope does not prove when it was signed, so a leaked key
+		// of an expired certificate could sign any provenance.
+		if !v.allowUnsigned {
+			return nil, fmt.Errorf("%w: a DSSE envelope has no transparency log entry or signed timestamp, "+
+				"expected a Sigstore bundle", errUnsigned)
+		}
+		return v.verifyEnvelope(content)
+	case sc.Type != "":
+		if !v.allowUnsigned {
+			return nil, fmt.Errorf("%w: expected a Sigstore bundle or a DSSE envelope", errUnsigned)
+		}
+		return content, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown provenance format", errSignature)
+	}
+}
+
+// loadTrustedMaterial returns the Sigstore trusted root. It is only loaded
+// when verifying signed provenance.
+func (v *ProvenanceVerifier) loadTrustedMaterial() (sigstoreRoot.TrustedMaterial, error) {
+	if v.trustedMaterial != nil {
+		return v.trustedMaterial, nil
+	}
+	var tr *sigstoreRoot.TrustedRoot
+	var err error
+	if v.trustedRootPath != "" {
+		tr, err = sigstoreRoot.NewTrustedRootFromPath(v.trustedRootPath)
+	} else {
+		tr, err = sigstoreRoot.FetchTrustedRoot()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading the Sigstore trusted root: %w", err)
+	}
+	v.trustedMaterial = tr
+	return tr, nil
+}
+
+// verifySignedEntity verifies a Sigstore signed entity, which must contain an
+// entry in the transparency log, and returns its DSSE payload.
+func (v *ProvenanceVerifier) verifySignedEntity(entity sigstoreVerify.SignedEntity) ([]byte, error) {
+	tm, err := v.loadTrustedMaterial()
+	if err != nil {
+		return nil, err
+	}
+	verifier, err := sigstoreVerify.NewSignedEntityVerifier(tm,
+		sigstoreVerify.WithTransparencyLog(1), sigstoreVerify.WithObserverTimestamps(1))
+	if err != nil {
+		return nil, fmt.Errorf("creating the Sigstore verifier: %w", err)
+	}
+	policy := sigstoreVerify.NewPolicy(sigstoreVerify.WithoutArtifactUnsafe(),
+		sigstoreVerify.WithCertificateIdentity(v.identity))
+	if _, err := verifier.Verify(entity, policy); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSignature, err)
+	}
+
+	sc, err := entity.SignatureContent()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errSignature, err)
+	}
+	env := sc.EnvelopeContent()
+	if env == nil {
+		return nil, fmt.Errorf("%w: expected a DSSE envelope in the bundle", errSignature)
+	}
+	return payload(env.RawEnvelope())
+}
+
+// verifyEnvelope verifies a DSSE envelope signed with the certificate that it
+// contains, as generated by the signers in signing/sigstore, and returns its
+// payload. The certificate chain is verified at the start of the validity of
+// the certificate, since the envelope does not contain a signed timestamp, so
+// it does not prove that the envelope was signed while the certificate was
+// valid. It is only used for insecure verification.
+func (v *ProvenanceVerifier) verifyEnvelope(content []byte) ([]byte, error) {
+	var env envelope.Envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		return nil, fmt.Errorf("%w: parsing the DSSE envelope: %w", errSignature, err)
+	}
+	if len(env.Signatures) != 1 {
+		return nil, fmt.Errorf("%w: expected exactly one signature in the envelope", errSignature)
+	}
+	certs, err := cryptoutils.UnmarshalCertificatesFromPEM([]byte(env.Signatures[0].Cert))
+	if err != nil || len(certs) == 0 {
+		return nil, fmt.Errorf("%w: expected a PEM encoded certificate in the envelope", errSignature)
+	}
+	cert := certs[0]
+
+	tm, err := v.loadTrustedMaterial()
+	if err != nil {
+		return nil, err
+	}
+	if err := sigstoreVerify.VerifyLeafCertificate(cert.NotBefore, cert, tm); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSignature, err)
+	}
+	summary, err := certificate.SummarizeCertificate(cert)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errSignature, err)
+	}
+	if err := v.identity.Verify(summary); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSignature, err)
+	}
+
+	rawEnv := &dsse.Envelope{PayloadType: env.PayloadType, Payload: env.Payload}
+	body, err := payload(rawEnv)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding the signature: %w", errSignature, err)
+	}
+	verifier, err := signature.LoadVerifier(cert.PublicKey, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", errSignature, err)
+	}
+	if err := verifier.VerifySignature(bytes.NewReader(sig),
+		bytes.NewReader(dsse.PAE(env.PayloadType, body))); err != nil {
+		return nil, fmt.Errorf("%w: %w", errSignature, err)
+	}
+	return body, nil
+}
+
+// payload returns the decoded payload of an in-toto DSSE envelope.
+func payload(env *dsse.Envelope) ([]byte, error) {
+	if env.PayloadType != intoto.PayloadType {
+		return nil, fmt.Errorf("%w: unexpected payload type %q", errSignature, env.PayloadType)
+	}
+	body, err := env.DecodeB64Payload()
+	if err != nil {
+		return nil, fmt.Errorf("%w: decoding the payload: %w", errSignature, err)
+	}
+	return body, nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"bytes"
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+	"github.com/secure-systems-lab/go-securesystemslib/dsse"
+	"github.com/sigstore/sigstore-go/pkg/testing/ca"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+
+	"github.com/slsa-framework/slsa-github-generator/signing/envelope"
+)
+
+const (
+	testBuilderIdentity = "builder@example.com"
+	testBuilderIssuer   = "https://issuer.example.com"
+	testStatement       = `{"_type":"https://in-toto.io/Statement/v1","subject":[],"predicateType":"https://slsa.dev/provenance/v1"}`
+)
+
+// newTestProvenanceVerifier returns a ProvenanceVerifier that trusts the
+// virtual Sigstore instance.
+func newTestProvenanceVerifier(t *testing.T, vs *ca.VirtualSigstore, identityRegexp string, allowUnsigned bool) *ProvenanceVerifier {
+	t.Helper()
+	v, err := NewProvenanceVerifier(identityRegexp, testBuilderIssuer, "", allowUnsigned)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v.trustedMaterial = vs
+	return v
+}
+
+// signEnvelope returns a DSSE envelope of the payload, signed with a leaf
+// certificate of the virtual Sigstore instance that is added to the envelope.
+func signEnvelope(t *testing.T, vs *ca.VirtualSigstore, payload []byte) []byte {
+	t.Helper()
+	cert, key, err := vs.GenerateLeafCert(testBuilderIdentity, testBuilderIssuer)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signer, err := signature.LoadECDSASigner(key, crypto.SHA256)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	sig, err := signer.SignMessage(bytes.NewReader(dsse.PAE(intoto.PayloadType, payload)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	certPEM, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	env, err := json.Marshal(envelope.Envelope{
+		PayloadType: intoto.PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []envelope.Signature{
+			{Sig: base64.StdEncoding.EncodeToString(sig), Cert: string(certPEM)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return env
+}
+
+func Test_ProvenanceVerifier_Verify(t *testing.T) {
+	t.Parallel()
+
+	vs, err := ca.NewVirtualSigstore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	signed := signEnvelope(t, vs, []byte(testStatement))
+
+	var tampered envelope.Envelope
+	if err := json.Unmarshal(signed, &tampered); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tampered.Payload = base64.StdEncoding.EncodeToString([]byte(`{"_type":"https://in-toto.io/Statement/v1"}`))
+	tamperedBytes, err := json.Marshal(tampered)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		expected       error
+		name           string
+		content        []byte
+		identityRegexp string
+		allowUnsigned  bool
+		want           []byte
+	}{
+		{
+			name:           "signed envelope",
+			content:        signed,
+			identityRegexp: `^builder@example\.com$`,
+			expected:       errUnsigned,
+		},
+		{
+			name:           "signed envelope allowed",
+			content:        signed,
+			identityRegexp: `^builder@example\.com$`,
+			allowUnsigned:  true,
+			want:           []byte(testStatement),
+		},
+		{
+			name:           "unexpected identity",
+			content:        signed,
+			identityRegexp: `^other@example\.com$`,
+			allowUnsigned:  true,
+			expected:       errSignature,
+		},
+		{
+			name:           "tampered payload",
+			content:        tamperedBytes,
+			identityRegexp: `^builder@example\.com$`,
+			allowUnsigned:  true,
+			expected:       errSignature,
+		},
+		{
+			name:           "unsigned statement",
+			content:        []byte(testStatement),
+			identityRegexp: `^builder@example\.com$`,
+			expected:       errUnsigned,
+		},
+		{
+			name:           "unsigned statement allowed",
+			content:        []byte(testStatement),
+			identityRegexp: `^builder@example\.com$`,
+			allowUnsigned:  true,
+			want:           []byte(testStatement),
+		},
+		{
+			name:           "unknown format",
+			content:        []byte(`{"foo": "bar"}`),
+			identityRegexp: `^builder@example\.com$`,
+			allowUnsigned:  true,
+			expected:       errSignature,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v := newTestProvenanceVerifier(t, vs, tt.identityRegexp, tt.allowUnsigned)
+			got, err := v.Verify(tt.content)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if diff := cmp.Diff(string(tt.want), string(got)); diff != "" {
+				t.Errorf("unexpected statement (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_ProvenanceVerifier_verifySignedEntity(t *testing.T) {
+	t.Parallel()
+
+	vs, err := ca.NewVirtualSigstore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entity, err := vs.Attest(testBuilderIdentity, testBuilderIssuer, []byte(testStatement))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	v := newTestProvenanceVerifier(t, vs, `^builder@example\.com$`, false)
+	got, err := v.verifySignedEntity(entity)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(testStatement, string(got)); diff != "" {
+		t.Errorf("unexpected statement (-want +got):\n%s", diff)
+	}
+
+	v = newTestProvenanceVerifier(t, vs, `^other@example\.com$`, false)
+	_, err = v.verifySignedEntity(entity)
+	if !errors.Is(err, errSignature) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errSignature, cmpopts.EquateErrors()))
+	}
+}
+
+func Test_ProvenanceVerifier_expiredCertificate(t *testing.T) {
+	t.Parallel()
+
+	vs, err := ca.NewVirtualSigstore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	v := newTestProvenanceVerifier(t, vs, `^builder@example\.com$`, false)
+
+	// The leaf certificates are valid for 10 minutes, and the entry is
+	// integrated in the transparency log an hour later.
+	entity, err := vs.AttestAtTime(testBuilderIdentity, testBuilderIssuer, []byte(testStatement), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := v.verifySignedEntity(entity); !errors.Is(err, errSignature) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errSignature, cmpopts.EquateErrors()))
+	}
+
+	// A DSSE envelope does not prove when it was signed, so it is rejected
+	// even though its signature is valid.
+	if _, err := v.Verify(signEnvelope(t, vs, []byte(testStatement))); !errors.Is(err, errUnsigned) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errUnsigned, cmpopts.EquateErrors()))
+	}
+}
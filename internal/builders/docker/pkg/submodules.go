@@ -0,0 +1,302 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+// This file contains the handling of the Git submodules and Git LFS objects
+// of the source repository, which are part of the sources of the build.
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+var (
+	// errGitSubmodule indicates that a submodule is not checked out at its pinned commit.
+	errGitSubmodule = errors.New("git submodule")
+
+	// errGitLFS indicates that the Git LFS objects are not checked out.
+	errGitLFS = errors.New("git lfs")
+)
+
+// Submodule is a Git submodule of the source repository, checked out at the
+// commit pinned by its parent repository.
+type Submodule struct {
+	// Path of the submodule relative to the root of the source repository.
+	Path string
+
+	// URL of the repository of the submodule.
+	URL string
+
+	// SHA1 Git commit digest pinned by the parent repository.
+	Commit string
+}
+
+// resourceDescriptor returns the submodule as a resolved dependency.
+func (s Submodule) resourceDescriptor() slsa1.ResourceDescriptor {
+	uri := s.URL
+	if strings.Contains(uri, "://") && !strings.HasPrefix(uri, "git+") {
+		uri = "git+" + uri
+	}
+	return slsa1.ResourceDescriptor{
+		Name:   s.Path,
+		URI:    uri,
+		Digest: map[string]string{"sha1": s.Commit},
+	}
+}
+
+// hasSubmodules returns true if the repo in the current directory declares
+// submodules.
+func hasSubmodules() bool {
+	_, err := os.Stat(".gitmodules")
+	return err == nil
+}
+
+// listSubmodules returns the initialized submodules of the repo in the
+// current directory, recursively, with the commits pinned by their parents.
+func listSubmodules() ([]Submodule, error) {
+	if !hasSubmodules() {
+		return nil, nil
+	}
+	// $displaypath and $sha1 are set by `git submodule foreach`.
+	out, err := exec.Command("git", "submodule", "foreach", "--quiet", "--recursive",
+		`printf '%s\t%s\t%s\n' "$displaypath" "$sha1" "$(git remote get-url origin)"`).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: listing the submodules: %v", errGitSubmodule, err)
+	}
+
+	var submodules []Submodule
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, "\t")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("%w: unexpected output %q", errGitSubmodule, line)
+		}
+		submodules = append(submodules, Submodule{Path: parts[0], Commit: parts[1], URL: parts[2]})
+	}
+	return submodules, nil
+}
+
+// submoduleStatus is the status of a submodule, as reported by
+// `git submodule status`.
+type submoduleStatus struct {
+	// State is ' ' if the submodule is checked out at its pinned commit, '-'
+	// if it is not initialized, '+' if it is checked out at another commit,
+	// and 'U' if it has merge conflicts.
+	State byte
+
+	// Commit checked out in the submodule, or pinned if it is not initialized.
+	Commit string
+
+	// Path of the submodule relative to the root of the source repository.
+	Path string
+}
+
+// listSubmoduleStatus returns the status of the submodules of the repo in the
+// current directory, recursively.
+func listSubmoduleStatus() ([]submoduleStatus, error) {
+	out, err := exec.Command("git", "submodule", "status", "--recursive").Output()
+	if err != nil {
+		return nil, fmt.Errorf("%w: getting the status of the submodules: %v", errGitSubmodule, err)
+	}
+
+	var statuses []submoduleStatus
+	for _, line := range strings.Split(string(out), "\n") {
+		if line == "" {
+			continue
+		}
+		// Each line is the state, followed by the commit and the path.
+		fields := strings.Fields(line[1:])
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("%w: unexpected status %q", errGitSubmodule, line)
+		}
+		statuses = append(statuses, submoduleStatus{State: line[0], Commit: fields[0], Path: fields[1]})
+	}
+	return statuses, nil
+}
+
+// initSubmodules initializes the submodules of the repo in the current
+// directory that are not initialized, e.g., when the repo was cloned without
+// `--recurse-submodules`. Nothing is done if a submodule is checked out at
+// another commit than its pinned commit, which verifySubmodules reports,
+// since updating the submodules would discard that checkout.
+func (c *GitClient) initSubmodules() error {
+	if !hasSubmodules() {
+		return nil
+	}
+	statuses, err := listSubmoduleStatus()
+	if err != nil {
+		return err
+	}
+	uninitialized := false
+	for _, s := range statuses {
+		switch s.State {
+		case ' ':
+		case '-':
+			uninitialized = true
+		default:
+			return nil
+		}
+	}
+	if !uninitialized {
+		return nil
+	}
+
+	log.Printf("Initializing the submodules at their pinned commits.")
+	if err := c.runGitCommand("submodule", "update", "--init", "--recursive"); err != nil {
+		return fmt.Errorf("%w: couldn't initialize the submodules: %w", errGitSubmodule, err)
+	}
+	return nil
+}
+
+// verifySubmodules checks that the submodules of the repo in the current
+// directory are initialized, and checked out at their pinned commits.
+func verifySubmodules() error {
+	if !hasSubmodules() {
+		return nil
+	}
+	statuses, err := listSubmoduleStatus()
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		switch s.State {
+		case ' ':
+			continue
+		case '-':
+			return fmt.Errorf("%w: %q is not initialized", errGitSubmodule, s.Path)
+		case '+':
+			return fmt.Errorf("%w: %q is checked out at %q, which is not the pinned commit",
+				errGitSubmodule, s.Path, s.Commit)
+		default:
+			return fmt.Errorf("%w: %q has merge conflicts", errGitSubmodule, s.Path)
+		}
+	}
+	return nil
+}
+
+// usesLFS returns true if any of the .gitattributes files of the repo in the
+// directory uses Git LFS.
+func usesLFS(dir string) (bool, error) {
+	// The wildcard of the pathspec also matches slashes.
+	out, err := exec.Command("git", "-C", dir, "ls-files", "-z", "--", "*.gitattributes").Output()
+	if err != nil {
+		return false, fmt.Errorf("%w: listing the .gitattributes files: %v", errGitLFS, err)
+	}
+	for _, name := range strings.Split(string(out), "\x00") {
+		if filepath.Base(name) != ".gitattributes" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return false, fmt.Errorf("%w: reading %q: %v", errGitLFS, name, err)
+		}
+		if bytes.Contains(content, []byte("filter=lfs")) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyLFS checks that the Git LFS objects of the repo in the directory are
+// checked out, rather than their pointers.
+func verifyLFS(dir string) error {
+	lfs, err := usesLFS(dir)
+	if err != nil || !lfs {
+		return err
+	}
+	out, err := exec.Command("git", "-C", dir, "lfs", "ls-files").Output()
+	if err != nil {
+		return fmt.Errorf("%w: the repo %q uses Git LFS, but listing its files failed: %v", errGitLFS, dir, err)
+	}
+	// Each line is the object ID, followed by `*` if the object is checked
+	// out or `-` if it is a pointer, and the path.
+	for _, line := range strings.Split(string(out), "\n") {
+		if fields := strings.SplitN(line, " ", 3); len(fields) == 3 && fields[1] == "-" {
+			return fmt.Errorf("%w: %q is not checked out in %q", errGitLFS, fields[2], dir)
+		}
+	}
+	return nil
+}
+
+// repoDirs returns the root of the repo in the current directory, followed by
+// the paths of its submodules.
+func repoDirs() ([]string, error) {
+	submodules, err := listSubmodules()
+	if err != nil {
+		return nil, err
+	}
+	dirs := []string{"."}
+	for _, s := range submodules {
+		dirs = append(dirs, filepath.FromSlash(s.Path))
+	}
+	return dirs, nil
+}
+
+// verifySubmodulesAndLFS checks that the submodules of the repo in the current
+// directory are checked out at their pinned commits, and that the Git LFS
+// objects of the repo and its submodules are checked out.
+func verifySubmodulesAndLFS() error {
+	if err := verifySubmodules(); err != nil {
+		return err
+	}
+	dirs, err := repoDirs()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		if err := verifyLFS(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fetchSubmodulesAndLFS fetches the submodules of the repo in the current
+// directory at their pinned commits, and the Git LFS objects of the repo and
+// its submodules.
+func (c *GitClient) fetchSubmodulesAndLFS() error {
+	if hasSubmodules() {
+		if err := c.runGitCommand("submodule", "update", "--init", "--recursive"); err != nil {
+			return fmt.Errorf("%w: couldn't fetch the submodules: %w", errGitSubmodule, err)
+		}
+	}
+	dirs, err := repoDirs()
+	if err != nil {
+		return err
+	}
+	for _, dir := range dirs {
+		lfs, err := usesLFS(dir)
+		if err != nil {
+			return err
+		}
+		if !lfs {
+			continue
+		}
+		if err := c.runGitCommand("-C", dir, "lfs", "pull"); err != nil {
+			return fmt.Errorf("%w: couldn't fetch the LFS objects of %q: %w", errGitLFS, dir, err)
+		}
+	}
+	return nil
+}
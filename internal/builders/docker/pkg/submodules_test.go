@@ -0,0 +1,196 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkg
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	slsa1 "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/v1"
+)
+
+func Test_GitClient_submodules(t *testing.T) {
+	// Submodules are cloned from local paths.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	dir := chdirTemp(t)
+	sub := filepath.Join(dir, "sub")
+	runGit(t, dir, "init", "-q", sub)
+	runGit(t, sub, "commit", "-q", "--allow-empty", "-m", "first")
+	first := runGit(t, sub, "rev-parse", "HEAD")
+	runGit(t, sub, "commit", "-q", "--allow-empty", "-m", "second")
+	second := runGit(t, sub, "rev-parse", "HEAD")
+
+	repo := filepath.Join(dir, "repo")
+	runGit(t, dir, "init", "-q", repo)
+	runGit(t, repo, "submodule", "add", "-q", sub, "third_party/sub")
+	runGit(t, repo, "commit", "-q", "-m", "add submodule")
+	commit := runGit(t, repo, "rev-parse", "HEAD")
+	runGit(t, repo, "bundle", "create", "-q", filepath.Join(dir, "repo.bundle"), "--all")
+
+	config := &DockerBuildConfig{
+		SourceRepo:   "file://" + filepath.ToSlash(filepath.Join(dir, "repo.bundle")),
+		SourceDigest: Digest{Alg: "sha1", Value: commit},
+	}
+	f, err := newFetcher(config)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	gc := f.(*GitClient)
+	defer gc.cleanupAllFiles()
+
+	info, err := gc.Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Submodule{{Path: "third_party/sub", URL: sub, Commit: second}}
+	if diff := cmp.Diff(want, info.Submodules); diff != "" {
+		t.Errorf("unexpected submodules (-want +got):\n%s", diff)
+	}
+
+	db := &DockerBuild{config: config, buildConfig: &BuildConfig{}, RepoInfo: info}
+	wantDeps := []slsa1.ResourceDescriptor{
+		sourceArtifact(config),
+		{Name: "third_party/sub", URI: sub, Digest: map[string]string{"sha1": second}},
+	}
+	if diff := cmp.Diff(wantDeps, db.CreateBuildDefinition().ResolvedDependencies); diff != "" {
+		t.Errorf("unexpected resolved dependencies (-want +got):\n%s", diff)
+	}
+
+	// A submodule that is not at its pinned commit fails the verification.
+	runGit(t, info.RepoRoot, "-C", "third_party/sub", "checkout", "-q", first)
+	if _, err := gc.verifyRefAndCommit(); !errors.Is(err, errGitSubmodule) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errGitSubmodule, cmpopts.EquateErrors()))
+	}
+}
+
+func Test_GitClient_uninitializedSubmodules(t *testing.T) {
+	// Submodules are cloned from local paths.
+	t.Setenv("GIT_CONFIG_COUNT", "1")
+	t.Setenv("GIT_CONFIG_KEY_0", "protocol.file.allow")
+	t.Setenv("GIT_CONFIG_VALUE_0", "always")
+
+	dir := chdirTemp(t)
+	sub := filepath.Join(dir, "sub")
+	writeFiles(t, sub, "README.md")
+	runGit(t, dir, "init", "-q", sub)
+	runGit(t, sub, "add", "-A")
+	runGit(t, sub, "commit", "-q", "-m", "initial")
+	subCommit := runGit(t, sub, "rev-parse", "HEAD")
+
+	repo := filepath.Join(dir, "repo")
+	runGit(t, dir, "init", "-q", repo)
+	runGit(t, repo, "submodule", "add", "-q", sub, "third_party/sub")
+	runGit(t, repo, "commit", "-q", "-m", "add submodule")
+	commit := runGit(t, repo, "rev-parse", "HEAD")
+
+	// The repo is checked out without its submodules.
+	checkout := filepath.Join(dir, "checkout")
+	runGit(t, dir, "clone", "-q", repo, checkout)
+	if err := os.Chdir(checkout); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &DockerBuildConfig{
+		SourceRepo:   "git+https://github.com/slsa-framework/example",
+		SourceDigest: Digest{Alg: "sha1", Value: commit},
+	}
+	gc, err := newGitClient(config, 0 /* depth */)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer gc.cleanupAllFiles()
+
+	info, err := gc.Fetch()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Submodule{{Path: "third_party/sub", URL: sub, Commit: subCommit}}
+	if diff := cmp.Diff(want, info.Submodules); diff != "" {
+		t.Errorf("unexpected submodules (-want +got):\n%s", diff)
+	}
+	if _, err := os.Stat(filepath.Join(checkout, "third_party", "sub", "README.md")); err != nil {
+		t.Errorf("the submodule was not checked out: %v", err)
+	}
+}
+
+func Test_Submodule_resourceDescriptor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "https://github.com/slsa-framework/example", want: "git+https://github.com/slsa-framework/example"},
+		{url: "git+https://github.com/slsa-framework/example", want: "git+https://github.com/slsa-framework/example"},
+		{url: "/src/example", want: "/src/example"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			t.Parallel()
+
+			got := Submodule{Path: "example", URL: tt.url, Commit: "abcd"}.resourceDescriptor()
+			want := slsa1.ResourceDescriptor{Name: "example", URI: tt.want, Digest: map[string]string{"sha1": "abcd"}}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("unexpected resource descriptor (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_verifyLFS(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q")
+	writeFiles(t, dir, "README.md")
+	runGit(t, dir, "add", "-A")
+
+	lfs, err := usesLFS(dir)
+	if err != nil || lfs {
+		t.Errorf("unexpected result: %t, %v", lfs, err)
+	}
+	if err := verifyLFS(dir); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "assets", ".gitattributes"),
+		[]byte("*.bin filter=lfs diff=lfs merge=lfs -text\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "-A")
+
+	lfs, err = usesLFS(dir)
+	if err != nil || !lfs {
+		t.Errorf("unexpected result: %t, %v", lfs, err)
+	}
+	if err := exec.Command("git", "lfs", "version").Run(); err == nil {
+		t.Skip("git lfs is installed")
+	}
+	// The repo uses LFS, but the objects cannot be checked out.
+	if err := verifyLFS(dir); !errors.Is(err, errGitLFS) {
+		t.Errorf("unexpected error: %v", cmp.Diff(err, errGitLFS, cmpopts.EquateErrors()))
+	}
+}
@@ -21,7 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"path"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 
@@ -39,39 +39,71 @@ func attestCmd(provider slsa.ClientProvider, check func(error),
 ) *cobra.Command {
 	var attPath string
 	var subjectsFilename string
+	var subjectPaths []string
+	var digestAlgorithms []string
+	var predicateType string
+	var predicateFilename string
+	var outputDir string
+	var nameTemplate string
+	var onCollision string
 
 	c := &cobra.Command{
 		Use:   "attest",
 		Short: "Create a signed SLSA provenance attestation from a Github Action",
 		Long: `Generate and sign SLSA provenance from a Github Action to form an attestation
 and create a Sigstore Bundle. This command assumes that it is being
-run in the context of a Github Actions workflow.`,
+run in the context of a Github Actions workflow.
 
-		Run: func(_ *cobra.Command, _ []string) {
+Subjects are either read from --subjects-filename, or computed by hashing the
+files given by --subject-path with each of --subject-digest-algorithms.
+
+If --predicate-type and --predicate-file are given, the provided predicate
+(e.g. an SBOM or test report) is attested to instead of the SLSA provenance.
+
+Unless --signature is given, the attestation is written to --output-dir with a
+name generated from --name-template. The template can use the fields .Name
+(subject base name, or "multiple"), .DigestPrefix, .BuildType (last element of
+the build type or predicate type URI) and .RunID.`,
+
+		Run: func(cmd *cobra.Command, _ []string) {
 			ghContext, err := github.GetWorkflowContext()
 			check(err)
 
 			varsContext, err := github.GetVarsContext()
 			check(err)
 
-			subjectsBytes, err := utils.SafeReadFile(subjectsFilename)
-			check(err)
-			parsedSubjects, err := parseSubjects(string(subjectsBytes))
-			check(err)
+			var parsedSubjects []intoto.Subject
+			switch {
+			case subjectsFilename != "" && len(subjectPaths) > 0:
+				check(errors.New("--subjects-filename and --subject-path cannot be used together"))
+			case len(subjectPaths) > 0:
+				parsedSubjects, err = subjectsFromFiles(subjectPaths, digestAlgorithms)
+				check(err)
+			default:
+				subjectsBytes, err := utils.SafeReadFile(subjectsFilename)
+				check(err)
+				parsedSubjects, err = parseSubjects(string(subjectsBytes))
+				check(err)
+			}
 			if len(parsedSubjects) == 0 {
 				check(errors.New("expected at least one subject"))
 			}
+			if (predicateType == "") != (predicateFilename == "") {
+				check(errors.New("--predicate-type and --predicate-file must be used together"))
+			}
+
+			collision, err := utils.ParseCollisionStrategy(onCollision)
+			check(err)
 
 			// NOTE: The provenance file path is untrusted and should be
-			// validated. This is done by CreateNewFileUnderCurrentDirectory.
+			// validated. This is done by CreateFileUnderCurrentDirectory.
 			if attPath == "" {
-				if len(parsedSubjects) == 1 {
-					filename := path.Base(parsedSubjects[0].Name)
-					attPath = fmt.Sprintf("%s.intoto.jsonl", filename)
-				} else {
-					// len(parsedSubjects) > 1
-					attPath = "multiple.intoto.jsonl"
-				}
+				data := newAttestationNameData(parsedSubjects, attestationBuildType(predicateType), ghContext.RunID)
+				name, err := attestationName(nameTemplate, data)
+				check(err)
+				attPath = filepath.Join(outputDir, name)
+			} else if cmd.Flags().Changed("output-dir") || cmd.Flags().Changed("name-template") {
+				check(errors.New("--signature cannot be used with --output-dir or --name-template"))
 			}
 
 			// Verify the extension path and extension.
@@ -80,45 +112,46 @@ run in the context of a Github Actions workflow.`,
 
 			ctx := context.Background()
 
-			b := common.GenericBuild{
-				GithubActionsBuild: slsa.NewGithubActionsBuild(parsedSubjects, &ghContext, varsContext),
-				BuildTypeURI:       provenanceOnlyBuildType,
-			}
-			if provider != nil {
-				b.WithClients(provider)
-			} else if utils.IsPresubmitTests() {
-				// TODO(github.com/slsa-framework/slsa-github-generator/issues/124): Remove
-				b.WithClients(&slsa.NilClientProvider{})
-			}
+			var statement *intoto.Statement
+			if predicateType != "" {
+				predicateBytes, err := utils.SafeReadFile(predicateFilename)
+				check(err)
+				predicate, err := parsePredicate(predicateType, predicateBytes)
+				check(err)
 
-			g := slsa.NewHostedActionsGenerator(&b)
-			if provider != nil {
-				g.WithClients(provider)
-			} else if utils.IsPresubmitTests() {
-				// TODO(github.com/slsa-framework/slsa-github-generator/issues/124): Remove
-				g.WithClients(&slsa.NilClientProvider{})
-			}
+				statement = &intoto.Statement{
+					StatementHeader: intoto.StatementHeader{
+						Type:          intoto.StatementInTotoV01,
+						PredicateType: predicateType,
+						Subject:       parsedSubjects,
+					},
+					Predicate: predicate,
+				}
+			} else {
+				p, err := generateProvenance(ctx, provider, parsedSubjects, &ghContext, varsContext)
+				check(err)
 
-			p, err := g.Generate(ctx)
-			check(err)
+				statement = &intoto.Statement{
+					StatementHeader: p.StatementHeader,
+					Predicate:       p.Predicate,
+				}
+			}
 
-			// Note: the path is validated within CreateNewFileUnderCurrentDirectory().
+			// Note: the path is validated within CreateFileUnderCurrentDirectory().
 			var attBytes []byte
 			if utils.IsPresubmitTests() {
-				attBytes, err = json.Marshal(p)
+				attBytes, err = json.Marshal(statement)
 				check(err)
 			} else {
-				att, err := signer.Sign(ctx, &intoto.Statement{
-					StatementHeader: p.StatementHeader,
-					Predicate:       p.Predicate,
-				})
+				att, err := signer.Sign(ctx, statement)
 				check(err)
 
 				attBytes = att.Bytes()
 			}
 
-			f, err := utils.CreateNewFileUnderCurrentDirectory(attPath, os.O_WRONLY)
+			f, createdPath, err := utils.CreateFileUnderCurrentDirectory(attPath, os.O_WRONLY, collision)
 			check(err)
+			attPath = createdPath
 
 			_, err = f.Write(attBytes)
 			check(err)
@@ -137,5 +170,61 @@ run in the context of a Github Actions workflow.`,
 		&subjectsFilename, "subjects-filename", "f", "",
 		"Filename containing a formatted list of subjects in the same format as sha256sum (base64 encoded).",
 	)
+	c.Flags().StringArrayVar(
+		&subjectPaths, "subject-path", nil,
+		"Path of a file to compute a subject from. Can be repeated. It must be under the current directory.",
+	)
+	c.Flags().StringSliceVar(
+		&digestAlgorithms, "subject-digest-algorithms", []string{utils.SHA256},
+		fmt.Sprintf("Algorithms used to compute the digests of --subject-path: %q, %q or %q.",
+			utils.SHA256, utils.SHA384, utils.SHA512),
+	)
+	c.Flags().StringVar(
+		&predicateType, "predicate-type", "",
+		"URI of the type of the predicate given by --predicate-file. SLSA provenance types are not allowed.",
+	)
+	c.Flags().StringVar(
+		&predicateFilename, "predicate-file", "",
+		"Filename containing a JSON predicate to attest to instead of the SLSA provenance.",
+	)
+	c.Flags().StringVar(
+		&outputDir, "output-dir", ".",
+		"Directory to write the signed provenance to. It must be under the current directory.",
+	)
+	c.Flags().StringVar(
+		&nameTemplate, "name-template", defaultNameTemplate,
+		"Go template for the name of the signed provenance file.",
+	)
+	c.Flags().StringVar(
+		&onCollision, "on-collision", string(utils.CollisionFail),
+		fmt.Sprintf("What to do if the provenance file already exists: %q, %q or %q.",
+			utils.CollisionFail, utils.CollisionOverwrite, utils.CollisionSuffix),
+	)
 	return c
 }
+
+// generateProvenance generates the SLSA provenance for the given subjects.
+func generateProvenance(ctx context.Context, provider slsa.ClientProvider, subjects []intoto.Subject,
+	ghContext *github.WorkflowContext, varsContext github.VarsContext,
+) (*intoto.ProvenanceStatement, error) {
+	b := common.GenericBuild{
+		GithubActionsBuild: slsa.NewGithubActionsBuild(subjects, ghContext, varsContext),
+		BuildTypeURI:       provenanceOnlyBuildType,
+	}
+	if provider != nil {
+		b.WithClients(provider)
+	} else if utils.IsPresubmitTests() {
+		// TODO(github.com/slsa-framework/slsa-github-generator/issues/124): Remove
+		b.WithClients(&slsa.NilClientProvider{})
+	}
+
+	g := slsa.NewHostedActionsGenerator(&b)
+	if provider != nil {
+		g.WithClients(provider)
+	} else if utils.IsPresubmitTests() {
+		// TODO(github.com/slsa-framework/slsa-github-generator/issues/124): Remove
+		g.WithClients(&slsa.NilClientProvider{})
+	}
+
+	return g.Generate(ctx)
+}
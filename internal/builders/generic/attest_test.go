@@ -208,6 +208,94 @@ func TestParseSubjects(t *testing.T) {
 	}
 }
 
+// Test_subjectsFromFiles tests the subjectsFromFiles function.
+func Test_subjectsFromFiles(t *testing.T) {
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	if err := os.MkdirAll("dist", 0o755); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join("dist", "artifact1"), []byte("foo\n"), 0o600); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	testCases := []struct {
+		name       string
+		paths      []string
+		algorithms []string
+		expected   []intoto.Subject
+		err        error
+	}{
+		{
+			name:  "default algorithm",
+			paths: []string{"./dist/artifact1"},
+			expected: []intoto.Subject{
+				{
+					Name: "dist/artifact1",
+					Digest: slsacommon.DigestSet{
+						"sha256": "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c",
+					},
+				},
+			},
+		},
+		{
+			name:       "multiple algorithms",
+			paths:      []string{"dist/artifact1"},
+			algorithms: []string{utils.SHA256, utils.SHA512},
+			expected: []intoto.Subject{
+				{
+					Name: "dist/artifact1",
+					Digest: slsacommon.DigestSet{
+						"sha256": "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c",
+						"sha512": "0cf9180a764aba863a67b6d72f0918bc131c6772642cb2dce5a34f0a702f9470" +
+							"ddc2bf125c12198b1995c233c34b4afd346c54a2334c350a948a51b6e8b4e6b6",
+					},
+				},
+			},
+		},
+		{
+			name:  "duplicate subject",
+			paths: []string{"dist/artifact1", "dist/../dist/artifact1"},
+			err:   errDuplicateSubject,
+		},
+		{
+			name:       "unsupported algorithm",
+			paths:      []string{"dist/artifact1"},
+			algorithms: []string{"md5"},
+			err:        utils.ErrHashAlgorithm,
+		},
+		{
+			name:  "path outside the current directory",
+			paths: []string{"../artifact1"},
+			err:   utils.ErrInvalidPath,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := subjectsFromFiles(tc.paths, tc.algorithms)
+			if !errors.Is(err, tc.err) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tc.err, cmpopts.EquateErrors()))
+			}
+			if diff := cmp.Diff(tc.expected, got); diff != "" {
+				t.Errorf("unexpected subjects (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func createTmpFile(content string) (string, error) {
 	file, err := os.CreateTemp(".", "test-")
 	if err != nil {
@@ -505,3 +593,41 @@ func Test_attestCmd_subdirectory_artifact(t *testing.T) {
 		t.Errorf("error checking file: %v", err)
 	}
 }
+
+func Test_attestCmd_subject_path(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	if err := os.WriteFile("artifact1", []byte("foo\n"), 0o600); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	c := attestCmd(&slsa.NilClientProvider{}, checkTest(t), &testutil.TestSigner{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		"--subject-path", "artifact1",
+		"--subject-digest-algorithms", "sha256,sha512",
+	})
+	if err := c.Execute(); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+
+	// check that the expected file exists.
+	if _, err := os.Stat(filepath.Join(dir, "artifact1.intoto.jsonl")); err != nil {
+		t.Errorf("error checking file: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+const (
+	// defaultNameTemplate is the default template for attestation file names.
+	defaultNameTemplate = "{{.Name}}.intoto.jsonl"
+
+	// digestPrefixLength is the number of hex characters in DigestPrefix.
+	digestPrefixLength = 12
+)
+
+// errNameTemplate indicates an invalid attestation name template.
+var errNameTemplate = errors.New("name template")
+
+// attestationNameData is the data available to attestation name templates.
+type attestationNameData struct {
+	// Name is the base name of the subject, or "multiple" if there is more
+	// than one subject.
+	Name string
+
+	// DigestPrefix is a prefix of the sha256 digest of the subject, or of its
+	// first digest in the order of the algorithm names if it has no sha256
+	// digest. If there is more than one subject, it is a prefix of the sha256
+	// digest of the sorted list of subjects.
+	DigestPrefix string
+
+	// BuildType is the last path element of the build type URI, e.g.
+	// `generic@v1`, or of the predicate type for attestations of a provided
+	// predicate, e.g. `Document` for `https://spdx.dev/Document`.
+	BuildType string
+
+	// RunID is the GitHub Actions workflow run ID.
+	RunID string
+}
+
+// attestationBuildType returns the URI identifying the kind of attestation:
+// the predicate type when a predicate is provided, or the build type of the
+// generated provenance otherwise.
+func attestationBuildType(predicateType string) string {
+	if predicateType != "" {
+		return predicateType
+	}
+	return provenanceOnlyBuildType
+}
+
+// newAttestationNameData returns the template data for the subjects.
+func newAttestationNameData(subjects []intoto.Subject, buildType, runID string) attestationNameData {
+	d := attestationNameData{
+		Name:      "multiple",
+		BuildType: path.Base(buildType),
+		RunID:     runID,
+	}
+	if len(subjects) == 1 {
+		d.Name = path.Base(subjects[0].Name)
+		d.DigestPrefix = truncate(subjectDigest(subjects[0]), digestPrefixLength)
+		return d
+	}
+
+	// The subjects are formatted like the output of sha256sum.
+	lines := make([]string, 0, len(subjects))
+	for _, s := range subjects {
+		lines = append(lines, fmt.Sprintf("%s  %s\n", subjectDigest(s), s.Name))
+	}
+	sort.Strings(lines)
+	d.DigestPrefix = truncate(fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(lines, "")))), digestPrefixLength)
+	return d
+}
+
+// subjectDigest returns the sha256 digest of the subject, or its first digest
+// in the order of the algorithm names if it has no sha256 digest, e.g. when
+// only sha512 digests are computed.
+func subjectDigest(s intoto.Subject) string {
+	if d, ok := s.Digest["sha256"]; ok {
+		return d
+	}
+	algs := make([]string, 0, len(s.Digest))
+	for alg := range s.Digest {
+		algs = append(algs, alg)
+	}
+	if len(algs) == 0 {
+		return ""
+	}
+	sort.Strings(algs)
+	return s.Digest[algs[0]]
+}
+
+// attestationName renders the name template for the subjects. The resulting
+// name must be a single path element with the `.intoto.jsonl` extension.
+func attestationName(nameTemplate string, data attestationNameData) (string, error) {
+	t, err := template.New("name").Option("missingkey=error").Parse(nameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errNameTemplate, err)
+	}
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("%w: %w", errNameTemplate, err)
+	}
+
+	name := sb.String()
+	if name != path.Base(name) || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("%w: %q is not a file name", errNameTemplate, name)
+	}
+	if !strings.HasSuffix(name, ".intoto.jsonl") || name == ".intoto.jsonl" {
+		return "", fmt.Errorf("%w: suffix of %q must be .intoto.jsonl", errNameTemplate, name)
+	}
+	return name, nil
+}
+
+// truncate returns the first n characters of s.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,196 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/testutil"
+	"github.com/slsa-framework/slsa-github-generator/slsa"
+)
+
+func Test_attestationName(t *testing.T) {
+	t.Parallel()
+
+	single := []intoto.Subject{
+		{Name: "dir/artifact1", Digest: map[string]string{"sha256": "b5bb9d8014a0f9b1d61e21e796d78dccdf1352f23cd32812f4850b878ae4944c"}},
+	}
+	multiple := []intoto.Subject{
+		{Name: "artifact2", Digest: map[string]string{"sha256": "7d865e959b2466918c9863afca942d0fb89d7c9ac0c99bafc3749504ded97730"}},
+		single[0],
+	}
+	sha512Single := []intoto.Subject{
+		{Name: "artifact1", Digest: map[string]string{"sha512": "0cf9180a764aba863a67b6d72f0918bc131c6772642cb2dce5a34f0a702f9470ddc2bf125c12198b1995c233c34b4afd346c54a2334c350a948a51b6e8b4e6b6"}},
+	}
+	sha512Multiple := []intoto.Subject{
+		{Name: "artifact2", Digest: map[string]string{"sha512": "cf83e1357eefb8bdf1542850d66d8007d620e4050b5715dc83f4a921d36ce9ce47d0d13c5d85f2b0ff8318d2877eec2f63b931bd47417a81a538327af927da3e"}},
+		sha512Single[0],
+	}
+
+	tests := []struct {
+		expected      error
+		name          string
+		template      string
+		predicateType string
+		subjects      []intoto.Subject
+		want          string
+	}{
+		{
+			name:     "default single",
+			template: defaultNameTemplate,
+			subjects: single,
+			want:     "artifact1.intoto.jsonl",
+		},
+		{
+			name:     "default multiple",
+			template: defaultNameTemplate,
+			subjects: multiple,
+			want:     "multiple.intoto.jsonl",
+		},
+		{
+			name:     "all fields",
+			template: "{{.Name}}-{{.DigestPrefix}}-{{.BuildType}}-{{.RunID}}.intoto.jsonl",
+			subjects: single,
+			want:     "artifact1-b5bb9d8014a0-generic@v1-1234.intoto.jsonl",
+		},
+		{
+			name:          "predicate build type",
+			template:      "{{.Name}}-{{.BuildType}}.intoto.jsonl",
+			predicateType: "https://spdx.dev/Document",
+			subjects:      single,
+			want:          "artifact1-Document.intoto.jsonl",
+		},
+		{
+			name:     "multiple digest prefix",
+			template: "{{.Name}}-{{.DigestPrefix}}.intoto.jsonl",
+			subjects: multiple,
+			want:     "multiple-936c0e29fbe9.intoto.jsonl",
+		},
+		{
+			name:     "sha512 digest prefix",
+			template: "{{.Name}}-{{.DigestPrefix}}.intoto.jsonl",
+			subjects: sha512Single,
+			want:     "artifact1-0cf9180a764a.intoto.jsonl",
+		},
+		{
+			name:     "sha512 multiple digest prefix",
+			template: "{{.Name}}-{{.DigestPrefix}}.intoto.jsonl",
+			subjects: sha512Multiple,
+			want:     "multiple-53d74f3f6714.intoto.jsonl",
+		},
+		{
+			name:     "invalid template",
+			template: "{{.Name.intoto.jsonl",
+			subjects: single,
+			expected: errNameTemplate,
+		},
+		{
+			name:     "unknown field",
+			template: "{{.Unknown}}.intoto.jsonl",
+			subjects: single,
+			expected: errNameTemplate,
+		},
+		{
+			name:     "path separator",
+			template: "dir/{{.Name}}.intoto.jsonl",
+			subjects: single,
+			expected: errNameTemplate,
+		},
+		{
+			name:     "invalid extension",
+			template: "{{.Name}}.json",
+			subjects: single,
+			expected: errNameTemplate,
+		},
+		{
+			name:     "extension only",
+			template: ".intoto.jsonl",
+			subjects: single,
+			expected: errNameTemplate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := newAttestationNameData(tt.subjects, attestationBuildType(tt.predicateType), "1234")
+			got, err := attestationName(tt.template, data)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if got != tt.want {
+				t.Errorf("unexpected name, got: %q, want: %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_attestCmd_output_dir_collision(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	fn, err := createTmpFile(base64.StdEncoding.EncodeToString([]byte(testHash)))
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer os.Remove(fn)
+
+	for _, want := range []string{"artifact1.prov.intoto.jsonl", "artifact1.prov-1.intoto.jsonl"} {
+		c := attestCmd(&slsa.NilClientProvider{}, checkTest(t), &testutil.TestSigner{})
+		c.SetOut(new(bytes.Buffer))
+		c.SetArgs([]string{
+			"--subjects-filename", fn,
+			"--output-dir", "attestations",
+			"--name-template", "{{.Name}}.prov.intoto.jsonl",
+			"--on-collision", "suffix",
+		})
+		if err := c.Execute(); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+
+		// check that the expected file exists.
+		if _, err := os.Stat(filepath.Join(dir, "attestations", want)); err != nil {
+			t.Errorf("error checking file: %v", err)
+		}
+	}
+}
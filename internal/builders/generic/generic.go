@@ -21,12 +21,15 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 	"testing"
 
 	intoto "github.com/in-toto/in-toto-golang/in_toto"
 	slsacommon "github.com/in-toto/in-toto-golang/in_toto/slsa_provenance/common"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/utils"
 )
 
 func checkExit(err error) {
@@ -123,3 +126,31 @@ func parseSubjects(b64Str string) ([]intoto.Subject, error) {
 
 	return parsed, nil
 }
+
+// subjectsFromFiles computes the subjects of the files at the given paths,
+// which must be under the current directory. The subjects are named using the
+// cleaned, slash-separated paths, and have a digest for each of the algorithms.
+func subjectsFromFiles(paths, algorithms []string) ([]intoto.Subject, error) {
+	names := map[string]bool{}
+	for _, p := range paths {
+		name := filepath.ToSlash(filepath.Clean(p))
+		if names[name] {
+			return nil, fmt.Errorf("%w: %q", errDuplicateSubject, name)
+		}
+		names[name] = true
+	}
+
+	digests, err := utils.HashFiles(paths, utils.HashOptions{Algorithms: algorithms})
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := make([]intoto.Subject, len(paths))
+	for i, p := range paths {
+		parsed[i] = intoto.Subject{
+			Name:   filepath.ToSlash(filepath.Clean(p)),
+			Digest: digests[i],
+		}
+	}
+	return parsed, nil
+}
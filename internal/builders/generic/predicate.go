@@ -0,0 +1,195 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+)
+
+const (
+	// predicateTestResult is the predicate type for in-toto test result attestations.
+	// See https://github.com/in-toto/attestation/blob/main/spec/predicates/test-result.md
+	predicateTestResult = "https://in-toto.io/attestation/test-result/v0.1"
+
+	// slsaProvenancePrefix is the common prefix of all SLSA provenance predicate types.
+	slsaProvenancePrefix = "https://slsa.dev/provenance/"
+)
+
+var (
+	// errPredicateType indicates an invalid or disallowed predicate type.
+	errPredicateType = errors.New("predicate type")
+
+	// errPredicate indicates a predicate that is not valid JSON or does not
+	// match the schema of its predicate type.
+	errPredicate = errors.New("predicate")
+)
+
+// predicateValidators holds schema checks for well-known predicate types.
+// Predicates of other types only need to be a valid JSON object.
+var predicateValidators = map[string]func(map[string]any) error{
+	intoto.PredicateSPDX:      validateSPDX,
+	intoto.PredicateCycloneDX: validateCycloneDX,
+	predicateTestResult:       validateTestResult,
+}
+
+// validatePredicateType checks that the predicate type is an absolute URI
+// and that it is not a SLSA provenance type. Provenance is only ever generated
+// by the builder itself and must not be supplied by the caller.
+func validatePredicateType(predicateType string) error {
+	u, err := url.Parse(predicateType)
+	if err != nil || !u.IsAbs() {
+		return fmt.Errorf("%w: %q is not an absolute URI", errPredicateType, predicateType)
+	}
+	if strings.HasPrefix(predicateType, slsaProvenancePrefix) {
+		return fmt.Errorf("%w: %q is reserved for provenance generated by the builder", errPredicateType, predicateType)
+	}
+	return nil
+}
+
+// parsePredicate validates the predicate in b against the given predicate
+// type and returns it unmodified so that it can be embedded in a statement.
+func parsePredicate(predicateType string, b []byte) (json.RawMessage, error) {
+	if err := validatePredicateType(predicateType); err != nil {
+		return nil, err
+	}
+
+	var predicate map[string]any
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	if err := d.Decode(&predicate); err != nil {
+		return nil, fmt.Errorf("%w: expected a JSON object: %w", errPredicate, err)
+	}
+	if d.More() {
+		return nil, fmt.Errorf("%w: unexpected data after the JSON object", errPredicate)
+	}
+	if predicate == nil {
+		return nil, fmt.Errorf("%w: expected a JSON object, got null", errPredicate)
+	}
+
+	if validate, ok := predicateValidators[predicateType]; ok {
+		if err := validate(predicate); err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", errPredicate, predicateType, err)
+		}
+	}
+
+	return json.RawMessage(bytes.TrimSpace(b)), nil
+}
+
+// validateSPDX checks the required top-level fields of an SPDX 2.x JSON document.
+func validateSPDX(p map[string]any) error {
+	version, err := requiredString(p, "spdxVersion")
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(version, "SPDX-") {
+		return fmt.Errorf("unexpected spdxVersion %q", version)
+	}
+	for _, field := range []string{"SPDXID", "name", "dataLicense", "documentNamespace"} {
+		if _, err := requiredString(p, field); err != nil {
+			return err
+		}
+	}
+	if _, ok := p["creationInfo"].(map[string]any); !ok {
+		return errors.New("missing creationInfo object")
+	}
+	return nil
+}
+
+// validateCycloneDX checks the required top-level fields of a CycloneDX JSON BOM.
+func validateCycloneDX(p map[string]any) error {
+	format, err := requiredString(p, "bomFormat")
+	if err != nil {
+		return err
+	}
+	if format != "CycloneDX" {
+		return fmt.Errorf("unexpected bomFormat %q", format)
+	}
+	if _, err := requiredString(p, "specVersion"); err != nil {
+		return err
+	}
+	if c, ok := p["components"]; ok {
+		if _, ok := c.([]any); !ok {
+			return errors.New("components must be an array")
+		}
+	}
+	return nil
+}
+
+// validateTestResult checks an in-toto test result predicate.
+func validateTestResult(p map[string]any) error {
+	result, err := requiredString(p, "result")
+	if err != nil {
+		return err
+	}
+	switch result {
+	case "PASSED", "WARNED", "FAILED":
+	default:
+		return fmt.Errorf("unexpected result %q, expected PASSED, WARNED or FAILED", result)
+	}
+
+	configuration, ok := p["configuration"].([]any)
+	if !ok {
+		return errors.New("missing configuration array")
+	}
+	for i, c := range configuration {
+		rd, ok := c.(map[string]any)
+		if !ok {
+			return fmt.Errorf("configuration[%d] is not a resource descriptor", i)
+		}
+		_, hasURI := rd["uri"]
+		_, hasDigest := rd["digest"]
+		_, hasContent := rd["content"]
+		if !hasURI && !hasDigest && !hasContent {
+			return fmt.Errorf("configuration[%d] must have one of uri, digest or content", i)
+		}
+	}
+
+	for _, field := range []string{"passedTests", "warnedTests", "failedTests"} {
+		v, ok := p[field]
+		if !ok {
+			continue
+		}
+		tests, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s must be an array", field)
+		}
+		for i, t := range tests {
+			if _, ok := t.(string); !ok {
+				return fmt.Errorf("%s[%d] must be a string", field, i)
+			}
+		}
+	}
+	return nil
+}
+
+// requiredString returns the non-empty string value of the given field.
+func requiredString(p map[string]any, field string) (string, error) {
+	v, ok := p[field]
+	if !ok {
+		return "", fmt.Errorf("missing %s", field)
+	}
+	s, ok := v.(string)
+	if !ok || s == "" {
+		return "", fmt.Errorf("%s must be a non-empty string", field)
+	}
+	return s, nil
+}
@@ -0,0 +1,321 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	intoto "github.com/in-toto/in-toto-golang/in_toto"
+
+	"github.com/slsa-framework/slsa-github-generator/internal/testutil"
+	"github.com/slsa-framework/slsa-github-generator/signing"
+	"github.com/slsa-framework/slsa-github-generator/slsa"
+)
+
+func Test_parsePredicate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expected      error
+		name          string
+		predicateType string
+		predicate     string
+	}{
+		{
+			name:          "custom type",
+			predicateType: "https://example.com/custom/v1",
+			predicate:     `{"foo": "bar"}`,
+		},
+		{
+			name:          "relative type",
+			predicateType: "custom/v1",
+			predicate:     `{"foo": "bar"}`,
+			expected:      errPredicateType,
+		},
+		{
+			name:          "slsa provenance type",
+			predicateType: "https://slsa.dev/provenance/v0.2",
+			predicate:     `{"foo": "bar"}`,
+			expected:      errPredicateType,
+		},
+		{
+			name:          "not json",
+			predicateType: "https://example.com/custom/v1",
+			predicate:     `not json`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "not an object",
+			predicateType: "https://example.com/custom/v1",
+			predicate:     `["foo"]`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "null",
+			predicateType: "https://example.com/custom/v1",
+			predicate:     `null`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "trailing data",
+			predicateType: "https://example.com/custom/v1",
+			predicate:     `{"foo": "bar"} {}`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "valid spdx",
+			predicateType: intoto.PredicateSPDX,
+			predicate: `{
+				"spdxVersion": "SPDX-2.3",
+				"SPDXID": "SPDXRef-DOCUMENT",
+				"name": "artifact1",
+				"dataLicense": "CC0-1.0",
+				"documentNamespace": "https://example.com/artifact1",
+				"creationInfo": {"created": "2023-01-01T00:00:00Z", "creators": ["Tool: test"]}
+			}`,
+		},
+		{
+			name:          "invalid spdx version",
+			predicateType: intoto.PredicateSPDX,
+			predicate: `{
+				"spdxVersion": "2.3",
+				"SPDXID": "SPDXRef-DOCUMENT",
+				"name": "artifact1",
+				"dataLicense": "CC0-1.0",
+				"documentNamespace": "https://example.com/artifact1",
+				"creationInfo": {}
+			}`,
+			expected: errPredicate,
+		},
+		{
+			name:          "spdx missing creation info",
+			predicateType: intoto.PredicateSPDX,
+			predicate: `{
+				"spdxVersion": "SPDX-2.3",
+				"SPDXID": "SPDXRef-DOCUMENT",
+				"name": "artifact1",
+				"dataLicense": "CC0-1.0",
+				"documentNamespace": "https://example.com/artifact1"
+			}`,
+			expected: errPredicate,
+		},
+		{
+			name:          "valid cyclonedx",
+			predicateType: intoto.PredicateCycloneDX,
+			predicate:     `{"bomFormat": "CycloneDX", "specVersion": "1.5", "components": []}`,
+		},
+		{
+			name:          "invalid cyclonedx format",
+			predicateType: intoto.PredicateCycloneDX,
+			predicate:     `{"bomFormat": "SPDX", "specVersion": "1.5"}`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "invalid cyclonedx components",
+			predicateType: intoto.PredicateCycloneDX,
+			predicate:     `{"bomFormat": "CycloneDX", "specVersion": "1.5", "components": {}}`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "valid test result",
+			predicateType: predicateTestResult,
+			predicate: `{
+				"result": "PASSED",
+				"configuration": [{"uri": "https://example.com/.github/workflows/test.yml"}],
+				"passedTests": ["TestFoo", "TestBar"]
+			}`,
+		},
+		{
+			name:          "invalid test result",
+			predicateType: predicateTestResult,
+			predicate:     `{"result": "OK", "configuration": [{"uri": "https://example.com"}]}`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "test result missing configuration",
+			predicateType: predicateTestResult,
+			predicate:     `{"result": "FAILED"}`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "test result empty resource descriptor",
+			predicateType: predicateTestResult,
+			predicate:     `{"result": "FAILED", "configuration": [{}]}`,
+			expected:      errPredicate,
+		},
+		{
+			name:          "test result invalid tests",
+			predicateType: predicateTestResult,
+			predicate:     `{"result": "FAILED", "configuration": [{"uri": "https://example.com"}], "failedTests": [1]}`,
+			expected:      errPredicate,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			p, err := parsePredicate(tt.predicateType, []byte(tt.predicate))
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if err != nil {
+				return
+			}
+			if !json.Valid(p) {
+				t.Errorf("invalid JSON predicate: %s", p)
+			}
+		})
+	}
+}
+
+// recordingSigner is a Signer that records the statement it signs.
+type recordingSigner struct {
+	statement *intoto.Statement
+}
+
+// Sign implements Signer.Sign.
+func (s *recordingSigner) Sign(_ context.Context, st *intoto.Statement) (signing.Attestation, error) {
+	s.statement = st
+	return &testutil.TestAttestation{}, nil
+}
+
+func Test_attestCmd_custom_predicate(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	fn, err := createTmpFile(base64.StdEncoding.EncodeToString([]byte(testHash)))
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	pn, err := createTmpFile(`{"bomFormat": "CycloneDX", "specVersion": "1.5"}`)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	s := &recordingSigner{}
+	c := attestCmd(&slsa.NilClientProvider{}, checkTest(t), s)
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		"--subjects-filename", fn,
+		"--predicate-type", intoto.PredicateCycloneDX,
+		"--predicate-file", pn,
+		"--signature", "artifact1.cdx.intoto.jsonl",
+	})
+	if err := c.Execute(); err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	if s.statement == nil {
+		t.Fatal("expected the statement to be signed")
+	}
+	if got, want := s.statement.PredicateType, intoto.PredicateCycloneDX; got != want {
+		t.Errorf("unexpected predicate type, got: %q, want: %q", got, want)
+	}
+	if got, want := len(s.statement.Subject), 1; got != want {
+		t.Fatalf("unexpected number of subjects, got: %d, want: %d", got, want)
+	}
+	if got, want := s.statement.Subject[0].Name, "artifact1"; got != want {
+		t.Errorf("unexpected subject name, got: %q, want: %q", got, want)
+	}
+	if _, err := os.Stat("artifact1.cdx.intoto.jsonl"); err != nil {
+		t.Errorf("error checking file: %v", err)
+	}
+}
+
+func Test_attestCmd_invalid_predicate(t *testing.T) {
+	t.Setenv("GITHUB_CONTEXT", "{}")
+	t.Setenv("VARS_CONTEXT", "{}")
+
+	// Change to temporary dir
+	currentDir, err := os.Getwd()
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	dir, err := os.MkdirTemp("", "")
+	if err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.Chdir(dir); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+	defer func() {
+		if err := os.Chdir(currentDir); err != nil {
+			t.Errorf("unexpected failure: %v", err)
+		}
+	}()
+
+	// A custom check function that checks the error type is the expected error type.
+	check := func(err error) {
+		if err != nil {
+			got, want := err, errPredicate
+			if !errors.Is(got, want) {
+				t.Fatalf("unexpected error, got: %v, want: %v", got, want)
+			}
+			// Check should exit the program so we skip the rest of the test if we got the expected error.
+			t.SkipNow()
+		}
+	}
+
+	fn, err := createTmpFile(base64.StdEncoding.EncodeToString([]byte(testHash)))
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+	pn, err := createTmpFile(`{"bomFormat": "SPDX"}`)
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	c := attestCmd(&slsa.NilClientProvider{}, check, &testutil.TestSigner{})
+	c.SetOut(new(bytes.Buffer))
+	c.SetArgs([]string{
+		"--subjects-filename", fn,
+		"--predicate-type", intoto.PredicateCycloneDX,
+		"--predicate-file", pn,
+	})
+	if err := c.Execute(); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+
+	// If no error occurs we catch it here. SkipNow will exit the test process so this code should be unreachable.
+	t.Errorf("expected an error to occur.")
+}
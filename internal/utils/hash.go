@@ -0,0 +1,176 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+)
+
+const (
+	// SHA256 is the name of the SHA-256 hash algorithm.
+	SHA256 = "sha256"
+
+	// SHA384 is the name of the SHA-384 hash algorithm.
+	SHA384 = "sha384"
+
+	// SHA512 is the name of the SHA-512 hash algorithm.
+	SHA512 = "sha512"
+)
+
+// ErrHashAlgorithm indicates an unsupported hash algorithm.
+var ErrHashAlgorithm = errors.New("hash algorithm")
+
+// newHash returns a new hash for the algorithm.
+func newHash(algorithm string) (hash.Hash, error) {
+	switch algorithm {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA384:
+		return sha512.New384(), nil
+	case SHA512:
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrHashAlgorithm, algorithm)
+	}
+}
+
+// HashReader computes the digests of the contents of r using each of the
+// algorithms, in a single pass. The contents are also copied to w, if it is
+// not nil. The digests are returned as hex-encoded strings indexed by
+// algorithm. The SHA-256 digest is computed if no algorithm is given.
+func HashReader(w io.Writer, r io.Reader, algorithms ...string) (map[string]string, error) {
+	if len(algorithms) == 0 {
+		algorithms = []string{SHA256}
+	}
+	hashes := make(map[string]hash.Hash, len(algorithms))
+	writers := make([]io.Writer, 0, len(algorithms)+1)
+	for _, alg := range algorithms {
+		if _, ok := hashes[alg]; ok {
+			continue
+		}
+		h, err := newHash(alg)
+		if err != nil {
+			return nil, err
+		}
+		hashes[alg] = h
+		writers = append(writers, h)
+	}
+	if w != nil {
+		writers = append(writers, w)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(writers...), r); err != nil {
+		return nil, fmt.Errorf("hashing: %w", err)
+	}
+
+	digests := make(map[string]string, len(hashes))
+	for alg, h := range hashes {
+		digests[alg] = hex.EncodeToString(h.Sum(nil))
+	}
+	return digests, nil
+}
+
+// HashOptions configures HashFiles.
+type HashOptions struct {
+	// Algorithms to compute the digests with. Defaults to SHA-256.
+	Algorithms []string
+
+	// Maximum number of files hashed concurrently. Defaults to the number
+	// of CPUs.
+	Workers int
+
+	// Output returns the writer the contents of the file at the given index
+	// are copied to while hashing. It is closed after hashing if it is an
+	// io.Closer other than os.Stdout. Files are not copied if Output is nil,
+	// or if it returns a nil writer.
+	Output func(i int) (io.Writer, error)
+}
+
+// HashFiles computes the digests of the files, which must be under the
+// current directory. The files are streamed rather than read into memory,
+// and hashed concurrently by a bounded pool of workers. The digests are
+// returned in the order of the paths.
+func HashFiles(paths []string, opts HashOptions) ([]map[string]string, error) {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+
+	digests := make([]map[string]string, len(paths))
+	errs := make([]error, len(paths))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				digests[i], errs[i] = hashFile(i, paths[i], opts)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return digests, nil
+}
+
+// hashFile computes the digests of the file at index i of HashFiles.
+func hashFile(i int, path string, opts HashOptions) (map[string]string, error) {
+	if err := PathIsUnderCurrentDirectory(path); err != nil {
+		return nil, fmt.Errorf("%w: PathIsUnderCurrentDirectory: %w", ErrInternal, err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %q: %w", path, err)
+	}
+	defer f.Close()
+
+	var w io.Writer
+	if opts.Output != nil {
+		if w, err = opts.Output(i); err != nil {
+			return nil, fmt.Errorf("creating the output of %q: %w", path, err)
+		}
+	}
+
+	digests, err := HashReader(w, f, opts.Algorithms...)
+	if c, ok := w.(io.Closer); ok && w != io.Writer(os.Stdout) {
+		if cerr := c.Close(); err == nil && cerr != nil {
+			err = fmt.Errorf("closing the output of %q: %w", path, cerr)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", path, err)
+	}
+	return digests, nil
+}
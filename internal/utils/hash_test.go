@@ -0,0 +1,164 @@
+// Copyright 2026 SLSA Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+const (
+	helloSHA256 = "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03"
+	helloSHA512 = "e7c22b994c59d9cf2b48e549b1e24666636045930d3da7c1acb299d1c3b7f931" +
+		"f94aae41edda2c2b207a36e10f8bcb8d45223e54878f5b316e7ce3b6bc019629"
+	worldSHA256 = "e258d248fda94c63753607f7c4494ee0fcbe92f1a76bfdac795c9d84101eb317"
+)
+
+func Test_HashReader(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expected   error
+		name       string
+		algorithms []string
+		want       map[string]string
+	}{
+		{
+			name: "default algorithm",
+			want: map[string]string{SHA256: helloSHA256},
+		},
+		{
+			name:       "multiple algorithms",
+			algorithms: []string{SHA256, SHA512, SHA256},
+			want:       map[string]string{SHA256: helloSHA256, SHA512: helloSHA512},
+		},
+		{
+			name:       "unsupported algorithm",
+			algorithms: []string{SHA256, "md5"},
+			expected:   ErrHashAlgorithm,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var buf bytes.Buffer
+			got, err := HashReader(&buf, strings.NewReader("hello\n"), tt.algorithms...)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected digests (-want +got):\n%s", diff)
+			}
+			if err == nil && buf.String() != "hello\n" {
+				t.Errorf("unexpected copy, got: %q, want: %q", buf.String(), "hello\n")
+			}
+		})
+	}
+}
+
+func Test_HashFiles(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(wd); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	var want []map[string]string
+	for i := range 20 {
+		path := fmt.Sprintf("file-%d", i)
+		content, digest := "hello\n", helloSHA256
+		if i%2 == 1 {
+			content, digest = "world\n", worldSHA256
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		want = append(want, map[string]string{SHA256: digest})
+	}
+
+	t.Run("ordered digests", func(t *testing.T) {
+		got, err := HashFiles(paths, HashOptions{Workers: 3})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(want, got); diff != "" {
+			t.Errorf("unexpected digests (-want +got):\n%s", diff)
+		}
+	})
+
+	t.Run("copies to output", func(t *testing.T) {
+		out, err := filepath.Abs("out")
+		if err != nil {
+			t.Fatal(err)
+		}
+		output := func(i int) (io.Writer, error) {
+			return CreateNewFileUnderDirectory(paths[i], out, os.O_WRONLY)
+		}
+		got, err := HashFiles(paths[:2], HashOptions{Algorithms: []string{SHA256, SHA512}, Output: output})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got[0][SHA512] != helloSHA512 {
+			t.Errorf("unexpected digest, got: %q, want: %q", got[0][SHA512], helloSHA512)
+		}
+		content, err := os.ReadFile(filepath.Join("out", paths[1]))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(content) != "world\n" {
+			t.Errorf("unexpected output, got: %q, want: %q", content, "world\n")
+		}
+	})
+
+	t.Run("path outside the current directory", func(t *testing.T) {
+		_, err := HashFiles([]string{paths[0], "../outside"}, HashOptions{})
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("unexpected error: %v", cmp.Diff(err, ErrInvalidPath, cmpopts.EquateErrors()))
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := HashFiles([]string{"missing"}, HashOptions{})
+		if !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("unexpected error: %v", cmp.Diff(err, os.ErrNotExist, cmpopts.EquateErrors()))
+		}
+	})
+
+	t.Run("no files", func(t *testing.T) {
+		got, err := HashFiles(nil, HashOptions{})
+		if err != nil || len(got) != 0 {
+			t.Errorf("unexpected result: %v, %v", got, err)
+		}
+	})
+}
@@ -18,9 +18,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 )
 
 var (
@@ -100,6 +102,142 @@ func CreateNewFileUnderCurrentDirectory(path string, flag int) (io.Writer, error
 	return fp, nil
 }
 
+// CollisionStrategy determines how to handle a file that already exists when
+// creating a new file.
+type CollisionStrategy string
+
+const (
+	// CollisionFail fails if the file already exists.
+	CollisionFail CollisionStrategy = "fail"
+
+	// CollisionOverwrite truncates and overwrites the existing file.
+	CollisionOverwrite CollisionStrategy = "overwrite"
+
+	// CollisionSuffix creates a new file with a numeric suffix added before
+	// the extension, e.g. `name-1.intoto.jsonl`.
+	CollisionSuffix CollisionStrategy = "suffix"
+)
+
+// maxCollisionSuffix is the largest suffix tried by CollisionSuffix.
+const maxCollisionSuffix = 1000
+
+// ParseCollisionStrategy parses and validates a CollisionStrategy.
+func ParseCollisionStrategy(s string) (CollisionStrategy, error) {
+	switch c := CollisionStrategy(s); c {
+	case CollisionFail, CollisionOverwrite, CollisionSuffix:
+		return c, nil
+	default:
+		return "", fmt.Errorf("%w: unsupported collision strategy %q", ErrInvalidPath, s)
+	}
+}
+
+// CreateFileUnderCurrentDirectory creates a file under the current directory,
+// creating its parent directories if needed. Existing files are handled
+// according to the strategy. The path of the created file is returned, which
+// differs from `path` when CollisionSuffix is used. The file is always
+// created with the permissions `0o600`.
+func CreateFileUnderCurrentDirectory(path string, flag int, strategy CollisionStrategy) (io.Writer, string, error) {
+	if path == "-" {
+		return os.Stdout, path, nil
+	}
+
+	if err := PathIsUnderCurrentDirectory(path); err != nil {
+		return nil, "", err
+	}
+
+	// The parent directories must not be symbolic links to a directory outside
+	// of the current directory. They are checked again once they are created.
+	path = filepath.Clean(path)
+	dir := filepath.Dir(path)
+	if err := resolvedDirIsUnderCurrentDirectory(dir); err != nil {
+		return nil, "", err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, "", fmt.Errorf("%w: os.MkdirAll(): %w", ErrInternal, err)
+	}
+	if err := resolvedDirIsUnderCurrentDirectory(dir); err != nil {
+		return nil, "", err
+	}
+
+	// New files are created with O_EXCL, so they are never opened through a
+	// symbolic link.
+	switch strategy {
+	case CollisionFail:
+		fp, err := CreateNewFileUnderCurrentDirectory(path, flag)
+		return fp, path, err
+	case CollisionOverwrite:
+		// Only regular files are overwritten, and O_NOFOLLOW makes opening a
+		// symbolic link fail, so that it cannot be used to write outside of the
+		// current directory.
+		fp, err := os.OpenFile(path, flag|os.O_CREATE|os.O_TRUNC|syscall.O_NOFOLLOW, 0o600)
+		if err != nil {
+			return nil, "", fmt.Errorf("%w: os.OpenFile(): %w", ErrInvalidPath, err)
+		}
+		if fi, err := fp.Stat(); err != nil || !fi.Mode().IsRegular() {
+			fp.Close()
+			return nil, "", fmt.Errorf("%w: %q is not a regular file", ErrInvalidPath, path)
+		}
+		return fp, path, nil
+	case CollisionSuffix:
+		base, ext := splitExt(path)
+		for i := 0; i <= maxCollisionSuffix; i++ {
+			p := path
+			if i > 0 {
+				p = fmt.Sprintf("%s-%d%s", base, i, ext)
+			}
+			fp, err := CreateNewFileUnderCurrentDirectory(p, flag)
+			if err == nil {
+				return fp, p, nil
+			}
+			if !errors.Is(err, os.ErrExist) {
+				return nil, "", err
+			}
+		}
+		return nil, "", fmt.Errorf("%w: too many existing files for %q", ErrInvalidPath, path)
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported collision strategy %q", ErrInternal, strategy)
+	}
+}
+
+// resolvedDirIsUnderCurrentDirectory checks whether the directory is under
+// the current working directory once its symbolic links are resolved. If the
+// directory does not exist, its closest existing parent is checked.
+func resolvedDirIsUnderCurrentDirectory(dir string) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("%w: os.Getwd(): %w", ErrInternal, err)
+	}
+	wd, err = filepath.EvalSymlinks(wd)
+	if err != nil {
+		return fmt.Errorf("%w: filepath.EvalSymlinks(): %w", ErrInternal, err)
+	}
+	p, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("%w: filepath.Abs(): %w", ErrInternal, err)
+	}
+	for {
+		resolved, err := filepath.EvalSymlinks(p)
+		if err == nil {
+			return checkPathUnderDir(resolved, wd)
+		}
+		parent := filepath.Dir(p)
+		if !errors.Is(err, fs.ErrNotExist) || parent == p {
+			return fmt.Errorf("%w: filepath.EvalSymlinks(): %w", ErrInternal, err)
+		}
+		p = parent
+	}
+}
+
+// splitExt splits the path into the part before the extension and the
+// extension. The `.intoto.jsonl` extension is kept as a whole.
+func splitExt(path string) (string, string) {
+	if base, ok := strings.CutSuffix(path, ".intoto.jsonl"); ok && filepath.Base(base) != "" {
+		return base, ".intoto.jsonl"
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext), ext
+}
+
 // CreateNewFileUnderDirectory create a new file under the current directory
 // and fails if the file already exists. The file is always created with the pemisisons
 // `0o600`. Ensures that the path does not exit out of the given directory.
@@ -221,6 +221,179 @@ func Test_CreateNewFileUnderCurrentDirectory(t *testing.T) {
 	}
 }
 
+func Test_CreateFileUnderCurrentDirectory(t *testing.T) {
+	tests := []struct {
+		expected     error
+		name         string
+		path         string
+		strategy     CollisionStrategy
+		existingPath bool
+		wantPath     string
+	}{
+		{
+			name:     "new file",
+			path:     "new_file",
+			strategy: CollisionFail,
+			wantPath: "new_file",
+		},
+		{
+			name:     "new file in sub-directory",
+			path:     "dir/new_file",
+			strategy: CollisionFail,
+			wantPath: "dir/new_file",
+		},
+		{
+			name:     "invalid path",
+			path:     "../some/invalid/file",
+			strategy: CollisionOverwrite,
+			expected: ErrInvalidPath,
+		},
+		{
+			name:         "existing file fail",
+			path:         "existing.intoto.jsonl",
+			strategy:     CollisionFail,
+			existingPath: true,
+			expected:     ErrInvalidPath,
+		},
+		{
+			name:         "existing file overwrite",
+			path:         "existing.intoto.jsonl",
+			strategy:     CollisionOverwrite,
+			existingPath: true,
+			wantPath:     "existing.intoto.jsonl",
+		},
+		{
+			name:         "existing file suffix",
+			path:         "dir/existing.intoto.jsonl",
+			strategy:     CollisionSuffix,
+			existingPath: true,
+			wantPath:     "dir/existing-1.intoto.jsonl",
+		},
+		{
+			name:         "existing file suffix other extension",
+			path:         "existing.json",
+			strategy:     CollisionSuffix,
+			existingPath: true,
+			wantPath:     "existing-1.json",
+		},
+		{
+			name:     "unknown strategy",
+			path:     "new_file",
+			strategy: "rename",
+			expected: ErrInternal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanup, err := tempWD()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := cleanup(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}()
+
+			if tt.existingPath {
+				if _, _, err := CreateFileUnderCurrentDirectory(tt.path, os.O_WRONLY, CollisionFail); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			_, path, err := CreateFileUnderCurrentDirectory(tt.path, os.O_WRONLY, tt.strategy)
+			if !errors.Is(err, tt.expected) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, tt.expected, cmpopts.EquateErrors()))
+			}
+			if err != nil {
+				return
+			}
+			if path != tt.wantPath {
+				t.Errorf("unexpected path, got: %q, want: %q", path, tt.wantPath)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("error checking file: %v", err)
+			}
+		})
+	}
+}
+
+func Test_CreateFileUnderCurrentDirectory_symlink(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		strategy CollisionStrategy
+	}{
+		{
+			name:     "symlinked file overwrite",
+			path:     "link.intoto.jsonl",
+			strategy: CollisionOverwrite,
+		},
+		{
+			name:     "symlinked file fail",
+			path:     "link.intoto.jsonl",
+			strategy: CollisionFail,
+		},
+		{
+			name:     "symlinked output directory overwrite",
+			path:     "out/new.intoto.jsonl",
+			strategy: CollisionOverwrite,
+		},
+		{
+			name:     "symlinked output directory suffix",
+			path:     "out/new.intoto.jsonl",
+			strategy: CollisionSuffix,
+		},
+		{
+			name:     "sub-directory of symlinked output directory",
+			path:     "out/dir/new.intoto.jsonl",
+			strategy: CollisionFail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleanup, err := tempWD()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				if err := cleanup(); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}()
+
+			// Links to a file and a directory outside of the current directory.
+			outside := t.TempDir()
+			target := filepath.Join(outside, "target")
+			if err := os.WriteFile(target, []byte("content"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(target, "link.intoto.jsonl"); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink(outside, "out"); err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = CreateFileUnderCurrentDirectory(tt.path, os.O_WRONLY, tt.strategy)
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Fatalf("unexpected error: %v", cmp.Diff(err, ErrInvalidPath, cmpopts.EquateErrors()))
+			}
+
+			entries, err := os.ReadDir(outside)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("unexpected files created outside of the current directory: %v", entries)
+			}
+			if content, err := os.ReadFile(target); err != nil || string(content) != "content" {
+				t.Errorf("unexpected content of the link target: %q, %v", content, err)
+			}
+		})
+	}
+}
+
 func Test_PathIsUnderDirectory(t *testing.T) {
 	tests := []struct {
 		expected error